@@ -34,6 +34,164 @@ type EurekaApplicationPaths struct {
 	Status string `json:"status,omitempty"`
 }
 
+// InstanceStatus is the status of an instance as seen by Eureka
+type InstanceStatus string
+
+const (
+	InstanceStatusUp           InstanceStatus = "UP"
+	InstanceStatusOutOfService InstanceStatus = "OUT_OF_SERVICE"
+	InstanceStatusDown         InstanceStatus = "DOWN"
+	InstanceStatusStarting     InstanceStatus = "STARTING"
+)
+
+type MaintenanceWindow struct {
+	// Start of the maintenance window
+	Start metav1.Time `json:"start"`
+
+	// End of the maintenance window
+	End metav1.Time `json:"end"`
+
+	// +kubebuilder:validation:Enum=OUT_OF_SERVICE;DOWN
+	// Status override applied to the instances during the window (defaults to OUT_OF_SERVICE)
+	// +optional
+	Status InstanceStatus `json:"status,omitempty"`
+}
+
+// AddressSource defines where the IP address of the instances comes from
+type AddressSource string
+
+const (
+	// AddressSourceHostname registers the ingress host as the IP address
+	AddressSourceHostname AddressSource = "Hostname"
+	// AddressSourceResolve resolves the ingress host to its IP address
+	AddressSourceResolve AddressSource = "Resolve"
+	// AddressSourceLoadBalancer uses the address in the ingress status.loadBalancer
+	AddressSourceLoadBalancer AddressSource = "LoadBalancer"
+)
+
+type DataCenterInfo struct {
+	// +kubebuilder:validation:Enum=MyOwn;Amazon
+	// Data center type of the instances, defaults to the environment configuration
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Amazon availability zone, defaults to the zone label of the backend nodes
+	// +optional
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+
+	// Amazon instance type, defaults to the instance type label of the backend nodes
+	// +optional
+	InstanceType string `json:"instanceType,omitempty"`
+
+	// Amazon public hostname, defaults to the ingress host
+	// +optional
+	PublicHostname string `json:"publicHostname,omitempty"`
+
+	// Amazon public IPv4, defaults to the instance IP address
+	// +optional
+	PublicIpv4 string `json:"publicIpv4,omitempty"`
+
+	// Amazon local hostname, defaults to the ingress host
+	// +optional
+	LocalHostname string `json:"localHostname,omitempty"`
+
+	// Amazon local IPv4, defaults to the instance IP address
+	// +optional
+	LocalIpv4 string `json:"localIpv4,omitempty"`
+}
+
+// ExpirationPolicy defines what happens to the resource once its registration expires
+type ExpirationPolicy string
+
+const (
+	// ExpirationPolicyDeregister deregisters the instances and keeps the resource, marked as expired
+	ExpirationPolicyDeregister ExpirationPolicy = "Deregister"
+	// ExpirationPolicyDelete deletes the resource, deregistering its instances. Resources controlled by another
+	// object, i.e an Ingress, are deregistered instead.
+	ExpirationPolicyDelete ExpirationPolicy = "Delete"
+)
+
+// ZoneSource defines which pods are used to detect the zone of the instances
+type ZoneSource string
+
+const (
+	// ZoneSourceBackend uses the nodes running the pods behind the ingress backend service
+	ZoneSourceBackend ZoneSource = "Backend"
+	// ZoneSourceIngressController uses the nodes running the ingress controller pods
+	ZoneSourceIngressController ZoneSource = "IngressController"
+)
+
+type ZoneDetection struct {
+	// +kubebuilder:validation:Enum=Backend;IngressController
+	// Pods whose nodes are used to detect the zone (defaults to Backend)
+	// +optional
+	Source ZoneSource `json:"source,omitempty"`
+
+	// Namespace of the ingress controller pods
+	// +optional
+	IngressControllerNamespace string `json:"ingressControllerNamespace,omitempty"`
+
+	// Label selector of the ingress controller pods
+	// +optional
+	IngressControllerSelector *metav1.LabelSelector `json:"ingressControllerSelector,omitempty"`
+}
+
+// ProbeSource is the probe of the workload a path is derived from
+type ProbeSource string
+
+const (
+	ProbeSourceReadiness ProbeSource = "Readiness"
+	ProbeSourceLiveness  ProbeSource = "Liveness"
+	ProbeSourceStartup   ProbeSource = "Startup"
+)
+
+// AsgStatus is the status of an ASG as seen by Eureka
+type AsgStatus string
+
+const (
+	AsgStatusEnabled  AsgStatus = "ENABLED"
+	AsgStatusDisabled AsgStatus = "DISABLED"
+)
+
+// PathsFromProbes derives the paths left empty in the spec from the probes of the Deployment or StatefulSet
+// behind the ingress backends
+type PathsFromProbes struct {
+	// Container whose probes are used (defaults to the first container with an HTTP readiness probe)
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// +kubebuilder:validation:Enum=Readiness;Liveness;Startup
+	// Probe the status path is derived from (defaults to Liveness). The eurek8s.com/status-path
+	// annotation of the workload takes precedence.
+	// +optional
+	StatusProbe ProbeSource `json:"statusProbe,omitempty"`
+}
+
+// StatusFromEndpoints drives the status of the instances from the ready endpoints of the backend services
+type StatusFromEndpoints struct {
+	// Time the endpoints must stay ready before the instances go back UP (defaults to 30s)
+	// +optional
+	Hysteresis *metav1.Duration `json:"hysteresis,omitempty"`
+}
+
+// EurekaApplicationEnvironment is an environment the application is registered into, along with its overrides
+type EurekaApplicationEnvironment struct {
+	// Name of the environment
+	Name string `json:"name"`
+
+	// Zone of the app in this environment, overrides the zone of the spec
+	// +optional
+	Zone string `json:"zone,omitempty"`
+
+	// Metadata registered in this environment, merged into the metadata of the spec
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Paths registered in this environment, each path set overrides the one of the spec
+	// +optional
+	Paths *EurekaApplicationPaths `json:"paths,omitempty"`
+}
+
 // EurekaApplicationSpec defines the desired state of EurekaApplication
 type EurekaApplicationSpec struct {
 	// Enable/Disable specific instance
@@ -43,31 +201,208 @@ type EurekaApplicationSpec struct {
 	// Environment that should be used to register the instance
 	Environment string `json:"environment,omitempty"`
 
+	// Environments to register the instances into at once, takes precedence over environment
+	// +optional
+	Environments []EurekaApplicationEnvironment `json:"environments,omitempty"`
+
 	// +kubebuilder:validation:MinLength=0
-	// Name of the app to be registered in Eureka
+	// Name of the app to be registered in Eureka. It is a Go template executed with .Name, .Namespace, .Labels
+	// and .NamespaceLabels (i.e orders-{{.Namespace}})
 	AppName string `json:"appName,omitempty"`
 
 	// +kubebuilder:validation:MinLength=0
 	// Name of the ingress app to be registered in Eureka
 	IngressName string `json:"ingressName,omitempty"`
 
-	// Zone of the app to be registered in Eureka
+	// Names of more ingresses to register, along with ingressName
+	// +optional
+	Ingresses []string `json:"ingresses,omitempty"`
+
+	// Selector of more ingresses of the namespace to register, along with ingressName and ingresses
+	// +optional
+	IngressSelector *metav1.LabelSelector `json:"ingressSelector,omitempty"`
+
+	// Template of the id of the instances (defaults to {{.AppName}}:{{.Host}}:{{.Port}}, lower cased).
+	// Templates are Go templates of .AppName, .Host, .Port, .Path, .Namespace, .Name, .Environment, .Ingress
+	// and .Workload, the name of the Deployment or StatefulSet behind the ingress backend
+	// +optional
+	InstanceID string `json:"instanceId,omitempty"`
+
+	// Template of the VIP address of the instances (defaults to the app name)
+	// +optional
+	VipAddress string `json:"vipAddress,omitempty"`
+
+	// Template of the secure VIP address of the instances (defaults to the app name)
+	// +optional
+	SecureVipAddress string `json:"secureVipAddress,omitempty"`
+
+	// Template of the name of the ASG of the instances
+	// +optional
+	AsgName string `json:"asgName,omitempty"`
+
+	// +kubebuilder:validation:Enum=ENABLED;DISABLED
+	// Status set on the ASGs of the instances through Eureka's ASG API, DISABLED taking the traffic off
+	// every instance of the ASGs at once. The ASGs are left as they are when empty. Requires asgName.
+	// +optional
+	AsgStatus AsgStatus `json:"asgStatus,omitempty"`
+
+	// Zone of the app to be registered in Eureka. Use "auto" to detect the zone of each instance
+	// from the topology.kubernetes.io/zone label of the nodes, or "no-zone" to omit it
 	Zone string `json:"zone,omitempty"`
 
+	// How the zone is detected when zone is "auto"
+	// +optional
+	ZoneDetection *ZoneDetection `json:"zoneDetection,omitempty"`
+
 	// Paths to register along with the instance
 	Paths EurekaApplicationPaths `json:"paths,omitempty"`
+
+	// Derive the healthcheck path from the readiness probe of the workload, and the status path from another
+	// probe, when they are not set in paths
+	// +optional
+	PathsFromProbes *PathsFromProbes `json:"pathsFromProbes,omitempty"`
+
+	// Override the status of the instances to DOWN while the services behind the ingresses have no ready
+	// endpoints, i.e when the workload is scaled to zero
+	// +optional
+	StatusFromEndpoints *StatusFromEndpoints `json:"statusFromEndpoints,omitempty"`
+
+	// Metadata to register along with the instance
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:Enum=UP;OUT_OF_SERVICE;DOWN
+	// Status override applied to every instance through Eureka's status API.
+	// Unlike disabled, the instances stay registered.
+	// +optional
+	Status InstanceStatus `json:"status,omitempty"`
+
+	// Scheduled window during which the status override is applied automatically
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+
+	// Data center info to register along with the instances, overrides the environment configuration
+	// +optional
+	DataCenterInfo *DataCenterInfo `json:"dataCenterInfo,omitempty"`
+
+	// +kubebuilder:validation:Enum=Hostname;Resolve;LoadBalancer
+	// Source of the IP address registered for the instances (defaults to Hostname)
+	// +optional
+	AddressSource AddressSource `json:"addressSource,omitempty"`
+
+	// Registers the instances as STARTING until every ingress has a load balancer address in its status.
+	// The LoadBalancer address source registers the host as the IP address meanwhile.
+	// +optional
+	WaitForLoadBalancer bool `json:"waitForLoadBalancer,omitempty"`
+
+	// Time to wait for the instances to be deregistered before removing the finalizer of a deleted
+	// resource (defaults to 5m). Set the eurek8s.com/force-delete annotation to skip waiting.
+	// +optional
+	DeregistrationTimeout *metav1.Duration `json:"deregistrationTimeout,omitempty"`
+
+	// Time the registration lasts from the creation of the resource
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Time the registration expires at, the earliest of ttl and expiresAt applies
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// +kubebuilder:validation:Enum=Deregister;Delete
+	// What happens once the registration expires (defaults to Deregister)
+	// +optional
+	ExpirationPolicy ExpirationPolicy `json:"expirationPolicy,omitempty"`
+}
+
+type EurekaInstanceStatus struct {
+	// Id of the instance registered in Eureka
+	InstanceID string `json:"instanceId"`
+
+	// Name of the app the instance is registered with
+	App string `json:"app,omitempty"`
+
+	// Name of the ingress the instance comes from
+	Ingress string `json:"ingress,omitempty"`
+
+	// Environment the instance is registered into
+	Environment string `json:"environment,omitempty"`
+
+	// Status applied to the instance
+	Status InstanceStatus `json:"status"`
+}
+
+// DeregistrationStatus reports the attempts to deregister the instances of a deleted resource
+type DeregistrationStatus struct {
+	// Number of deregistration attempts
+	Attempts int32 `json:"attempts"`
+
+	// Time of the last deregistration attempt
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// Error of the last deregistration attempt
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
 // EurekaApplicationStatus defines the observed state of EurekaApplication
 type EurekaApplicationStatus struct {
+	// Last time the reconcile changed the status
 	LastReconcileTime *metav1.Time `json:"lastReconcileTime,omitempty"`
+
+	// Generation of the spec reflected by the status
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Status currently applied to the registered instances, the worst one across the environments
+	Status InstanceStatus `json:"status,omitempty"`
+
+	// Instances registered in Eureka
+	Instances []EurekaInstanceStatus `json:"instances,omitempty"`
+
+	// Status applied to the ASGs of the instances in every environment, empty until applied
+	// +optional
+	AsgStatus AsgStatus `json:"asgStatus,omitempty"`
+
+	// Spec used by the last reconcile, with the defaults of the namespace, the cluster and the controller applied
+	// +optional
+	EffectiveSpec *EurekaApplicationSpec `json:"effectiveSpec,omitempty"`
+
+	// Progress of the deregistration of the instances once the resource is deleted
+	// +optional
+	Deregistration *DeregistrationStatus `json:"deregistration,omitempty"`
+
+	// Conditions of the resource
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+const (
+	// ConditionTypeConflict is True when instances of the resource are registered by another resource
+	ConditionTypeConflict = "Conflict"
+
+	// ConditionTypeRegistered is True when the instances are registered, its reason is the category of the error otherwise
+	ConditionTypeRegistered = "Registered"
+
+	// ConditionTypeFrozen is True when registrations to an environment of the resource are queued by a freeze
+	ConditionTypeFrozen = "Frozen"
+
+	// ConditionTypeExpired is True once the registration expired, it is only set for resources with an expiry
+	ConditionTypeExpired = "Expired"
+
+	// ConditionTypeEndpointsReady is True when the backend services have ready endpoints, it is only set for
+	// resources with statusFromEndpoints
+	ConditionTypeEndpointsReady = "EndpointsReady"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="App",type=string,JSONPath=".spec.appName",description="Name of the eureka application"
 // +kubebuilder:printcolumn:name="Environment",type=string,JSONPath=".spec.environment",description="Environment key of the eureka application"
 // +kubebuilder:printcolumn:name="Ingress Name",type=string,JSONPath=".spec.ingressName",description="Name of the ingress"
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=".status.status",description="Status applied to the eureka instances"
 // +kubebuilder:printcolumn:name="Last Reconcile",type=date,JSONPath=".status.lastReconcileTime",description="Last reconcile time for this resource"
 
 // EurekaApplication is the Schema for the eurekaapplications API
@@ -0,0 +1,99 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EurekaApplicationDefaultsSpec holds the values used for the fields left empty by the applications
+type EurekaApplicationDefaultsSpec struct {
+	// Environment of the applications setting neither environment nor environments
+	// +optional
+	Environment string `json:"environment,omitempty"`
+
+	// Zone of the applications
+	// +optional
+	Zone string `json:"zone,omitempty"`
+
+	// Paths of the applications, each path being defaulted on its own
+	// +optional
+	Paths EurekaApplicationPaths `json:"paths,omitempty"`
+
+	// Metadata of the applications, merged with the metadata of each application
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Data center info of the applications
+	// +optional
+	DataCenterInfo *DataCenterInfo `json:"dataCenterInfo,omitempty"`
+
+	// Deregistration timeout of the applications
+	// +optional
+	DeregistrationTimeout *metav1.Duration `json:"deregistrationTimeout,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=eurekaapplicationdefaults
+// +kubebuilder:printcolumn:name="Environment",type=string,JSONPath=".spec.environment",description="Default environment"
+// +kubebuilder:printcolumn:name="Zone",type=string,JSONPath=".spec.zone",description="Default zone"
+
+// EurekaApplicationDefaults holds the defaults of the EurekaApplications of its namespace
+type EurekaApplicationDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EurekaApplicationDefaultsSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// EurekaApplicationDefaultsList contains a list of EurekaApplicationDefaults
+type EurekaApplicationDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EurekaApplicationDefaults `json:"items"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=clustereurekaapplicationdefaults,scope=Cluster
+// +kubebuilder:printcolumn:name="Environment",type=string,JSONPath=".spec.environment",description="Default environment"
+// +kubebuilder:printcolumn:name="Zone",type=string,JSONPath=".spec.zone",description="Default zone"
+
+// ClusterEurekaApplicationDefaults holds the defaults of every EurekaApplication, below the defaults of their namespace
+type ClusterEurekaApplicationDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EurekaApplicationDefaultsSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterEurekaApplicationDefaultsList contains a list of ClusterEurekaApplicationDefaults
+type ClusterEurekaApplicationDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterEurekaApplicationDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(
+		&EurekaApplicationDefaults{}, &EurekaApplicationDefaultsList{},
+		&ClusterEurekaApplicationDefaults{}, &ClusterEurekaApplicationDefaultsList{},
+	)
+}
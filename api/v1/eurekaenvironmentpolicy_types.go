@@ -0,0 +1,66 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EurekaEnvironmentPolicySpec allows namespaces and app names to register into environments
+type EurekaEnvironmentPolicySpec struct {
+	// Environments restricted by the policy. Only the namespaces and app names allowed by one of the policies
+	// of a restricted environment can register into it, environments without policy are not restricted.
+	// +kubebuilder:validation:MinItems=1
+	Environments []string `json:"environments"`
+
+	// Names of the namespaces allowed
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Labels of the namespaces allowed
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Patterns of the app names allowed (i.e payments-*), regardless of the case, any app name when empty
+	// +optional
+	AppNames []string `json:"appNames,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=eurekaenvironmentpolicies,scope=Cluster
+// +kubebuilder:printcolumn:name="Environments",type=string,JSONPath=".spec.environments",description="Environments restricted by the policy"
+
+// EurekaEnvironmentPolicy restricts the namespaces and app names registering into environments
+type EurekaEnvironmentPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EurekaEnvironmentPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// EurekaEnvironmentPolicyList contains a list of EurekaEnvironmentPolicy
+type EurekaEnvironmentPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EurekaEnvironmentPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EurekaEnvironmentPolicy{}, &EurekaEnvironmentPolicyList{})
+}
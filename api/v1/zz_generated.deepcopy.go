@@ -22,15 +22,108 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEurekaApplicationDefaults) DeepCopyInto(out *ClusterEurekaApplicationDefaults) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEurekaApplicationDefaults.
+func (in *ClusterEurekaApplicationDefaults) DeepCopy() *ClusterEurekaApplicationDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEurekaApplicationDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEurekaApplicationDefaults) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEurekaApplicationDefaultsList) DeepCopyInto(out *ClusterEurekaApplicationDefaultsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterEurekaApplicationDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEurekaApplicationDefaultsList.
+func (in *ClusterEurekaApplicationDefaultsList) DeepCopy() *ClusterEurekaApplicationDefaultsList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEurekaApplicationDefaultsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEurekaApplicationDefaultsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataCenterInfo) DeepCopyInto(out *DataCenterInfo) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataCenterInfo.
+func (in *DataCenterInfo) DeepCopy() *DataCenterInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(DataCenterInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeregistrationStatus) DeepCopyInto(out *DeregistrationStatus) {
+	*out = *in
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeregistrationStatus.
+func (in *DeregistrationStatus) DeepCopy() *DeregistrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeregistrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EurekaApplication) DeepCopyInto(out *EurekaApplication) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -52,6 +145,124 @@ func (in *EurekaApplication) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaApplicationDefaults) DeepCopyInto(out *EurekaApplicationDefaults) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaApplicationDefaults.
+func (in *EurekaApplicationDefaults) DeepCopy() *EurekaApplicationDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaApplicationDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EurekaApplicationDefaults) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaApplicationDefaultsList) DeepCopyInto(out *EurekaApplicationDefaultsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EurekaApplicationDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaApplicationDefaultsList.
+func (in *EurekaApplicationDefaultsList) DeepCopy() *EurekaApplicationDefaultsList {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaApplicationDefaultsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EurekaApplicationDefaultsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaApplicationDefaultsSpec) DeepCopyInto(out *EurekaApplicationDefaultsSpec) {
+	*out = *in
+	out.Paths = in.Paths
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DataCenterInfo != nil {
+		in, out := &in.DataCenterInfo, &out.DataCenterInfo
+		*out = new(DataCenterInfo)
+		**out = **in
+	}
+	if in.DeregistrationTimeout != nil {
+		in, out := &in.DeregistrationTimeout, &out.DeregistrationTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaApplicationDefaultsSpec.
+func (in *EurekaApplicationDefaultsSpec) DeepCopy() *EurekaApplicationDefaultsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaApplicationDefaultsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaApplicationEnvironment) DeepCopyInto(out *EurekaApplicationEnvironment) {
+	*out = *in
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = new(EurekaApplicationPaths)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaApplicationEnvironment.
+func (in *EurekaApplicationEnvironment) DeepCopy() *EurekaApplicationEnvironment {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaApplicationEnvironment)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EurekaApplicationList) DeepCopyInto(out *EurekaApplicationList) {
 	*out = *in
@@ -102,7 +313,70 @@ func (in *EurekaApplicationPaths) DeepCopy() *EurekaApplicationPaths {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EurekaApplicationSpec) DeepCopyInto(out *EurekaApplicationSpec) {
 	*out = *in
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]EurekaApplicationEnvironment, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Ingresses != nil {
+		in, out := &in.Ingresses, &out.Ingresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.IngressSelector != nil {
+		in, out := &in.IngressSelector, &out.IngressSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ZoneDetection != nil {
+		in, out := &in.ZoneDetection, &out.ZoneDetection
+		*out = new(ZoneDetection)
+		(*in).DeepCopyInto(*out)
+	}
 	out.Paths = in.Paths
+	if in.PathsFromProbes != nil {
+		in, out := &in.PathsFromProbes, &out.PathsFromProbes
+		*out = new(PathsFromProbes)
+		**out = **in
+	}
+	if in.StatusFromEndpoints != nil {
+		in, out := &in.StatusFromEndpoints, &out.StatusFromEndpoints
+		*out = new(StatusFromEndpoints)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DataCenterInfo != nil {
+		in, out := &in.DataCenterInfo, &out.DataCenterInfo
+		*out = new(DataCenterInfo)
+		**out = **in
+	}
+	if in.DeregistrationTimeout != nil {
+		in, out := &in.DeregistrationTimeout, &out.DeregistrationTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaApplicationSpec.
@@ -122,6 +396,28 @@ func (in *EurekaApplicationStatus) DeepCopyInto(out *EurekaApplicationStatus) {
 		in, out := &in.LastReconcileTime, &out.LastReconcileTime
 		*out = (*in).DeepCopy()
 	}
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = make([]EurekaInstanceStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.EffectiveSpec != nil {
+		in, out := &in.EffectiveSpec, &out.EffectiveSpec
+		*out = new(EurekaApplicationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deregistration != nil {
+		in, out := &in.Deregistration, &out.Deregistration
+		*out = new(DeregistrationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaApplicationStatus.
@@ -133,3 +429,183 @@ func (in *EurekaApplicationStatus) DeepCopy() *EurekaApplicationStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaEnvironmentPolicy) DeepCopyInto(out *EurekaEnvironmentPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaEnvironmentPolicy.
+func (in *EurekaEnvironmentPolicy) DeepCopy() *EurekaEnvironmentPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaEnvironmentPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EurekaEnvironmentPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaEnvironmentPolicyList) DeepCopyInto(out *EurekaEnvironmentPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EurekaEnvironmentPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaEnvironmentPolicyList.
+func (in *EurekaEnvironmentPolicyList) DeepCopy() *EurekaEnvironmentPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaEnvironmentPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EurekaEnvironmentPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaEnvironmentPolicySpec) DeepCopyInto(out *EurekaEnvironmentPolicySpec) {
+	*out = *in
+	if in.Environments != nil {
+		in, out := &in.Environments, &out.Environments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppNames != nil {
+		in, out := &in.AppNames, &out.AppNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaEnvironmentPolicySpec.
+func (in *EurekaEnvironmentPolicySpec) DeepCopy() *EurekaEnvironmentPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaEnvironmentPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EurekaInstanceStatus) DeepCopyInto(out *EurekaInstanceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EurekaInstanceStatus.
+func (in *EurekaInstanceStatus) DeepCopy() *EurekaInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EurekaInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PathsFromProbes) DeepCopyInto(out *PathsFromProbes) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathsFromProbes.
+func (in *PathsFromProbes) DeepCopy() *PathsFromProbes {
+	if in == nil {
+		return nil
+	}
+	out := new(PathsFromProbes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatusFromEndpoints) DeepCopyInto(out *StatusFromEndpoints) {
+	*out = *in
+	if in.Hysteresis != nil {
+		in, out := &in.Hysteresis, &out.Hysteresis
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatusFromEndpoints.
+func (in *StatusFromEndpoints) DeepCopy() *StatusFromEndpoints {
+	if in == nil {
+		return nil
+	}
+	out := new(StatusFromEndpoints)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneDetection) DeepCopyInto(out *ZoneDetection) {
+	*out = *in
+	if in.IngressControllerSelector != nil {
+		in, out := &in.IngressControllerSelector, &out.IngressControllerSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneDetection.
+func (in *ZoneDetection) DeepCopy() *ZoneDetection {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneDetection)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -18,23 +18,35 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	discoveryv1 "github.com/eurek8s/controller/api/v1"
 	eurekahandler "github.com/eurek8s/controller/internal/eureka/handler"
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 )
 
 const (
 	eventType           = v1.EventTypeWarning
-	eventReasonNotFound = "NotFound"
+	eventReasonConflict = "Conflict"
 )
 
 // EurekaApplicationReconciler reconciles a EurekaApplication object
@@ -45,11 +57,21 @@ type EurekaApplicationReconciler struct {
 
 	EventRecorder record.EventRecorder
 	EurekaHandler *eurekahandler.Handler
+
+	rateLimiter *categoryRateLimiter
 }
 
 //+kubebuilder:rbac:groups=discovery.eurek8s.com,resources=eurekaapplications,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=discovery.eurek8s.com,resources=eurekaapplications/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=discovery.eurek8s.com,resources=eurekaapplications/finalizers,verbs=update
+//+kubebuilder:rbac:groups=discovery.eurek8s.com,resources=eurekaapplicationdefaults;clustereurekaapplicationdefaults,verbs=get;list;watch
+//+kubebuilder:rbac:groups=discovery.eurek8s.com,resources=eurekaenvironmentpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=services;endpoints,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes;pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;replicasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",namespace=system,resources=configmaps,verbs=get;create;update
 
 func (r *EurekaApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("eurekaapplication", req.NamespacedName)
@@ -63,38 +85,206 @@ func (r *EurekaApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	previous := eurekaApp.Status.DeepCopy()
+
 	if err := r.EurekaHandler.Handle(ctx, r.Client, &eurekaApp, req.String()); err != nil {
-		if apierrors.IsNotFound(err) {
-			switch t := err.(type) {
-			case apierrors.APIStatus:
-				d := t.Status().Details
-				message := fmt.Sprintf("Referenced object not found %s/%s", d.Kind, d.Name)
-				r.EventRecorder.Event(&eurekaApp, eventType, eventReasonNotFound, message)
-			default:
-				r.EventRecorder.Event(&eurekaApp, eventType, eventReasonNotFound, "Referenced object not found")
+		category := eurekahandler.Classify(err)
+		log.Error(err, "unable to reconcile resource", "category", category)
+
+		message := err.Error()
+		if category == eurekahandler.CategoryNotFound {
+			message = "Referenced object not found"
+			var status apierrors.APIStatus
+			if errors.As(err, &status) && status.Status().Details != nil {
+				d := status.Status().Details
+				message = fmt.Sprintf("Referenced object not found %s/%s", d.Kind, d.Name)
 			}
+		}
+		r.EventRecorder.Event(&eurekaApp, eventType, string(category), message)
 
-			log.Error(err, "unable to fetch resource")
+		// environments registered before the failure are still reported
+		if err := r.updateStatus(ctx, &eurekaApp, previous); err != nil {
+			log.Error(err, "unable to update eureka application status")
 		}
 
-		//return ctrl.Result{RequeueAfter: 30 * time.Second}, client.IgnoreNotFound(err)
-		r.Log.Info("re-queueing to run after 30s...")
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		// retried with the backoff policy of the category
+		r.rateLimiter.categorize(req, category)
+		return ctrl.Result{Requeue: true}, nil
+	} else if !eurekaApp.DeletionTimestamp.IsZero() {
+		// the resource is gone once its finalizer is removed
+		return ctrl.Result{}, nil
 	} else {
-		eurekaApp.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
-		log.Info("updating EurekaApplication lastReconcileTime...")
-		if err := r.Status().Update(ctx, &eurekaApp); err != nil {
+		c := meta.FindStatusCondition(eurekaApp.Status.Conditions, discoveryv1.ConditionTypeConflict)
+		p := meta.FindStatusCondition(previous.Conditions, discoveryv1.ConditionTypeConflict)
+		if c != nil && c.Status == metav1.ConditionTrue && (p == nil || p.Status != c.Status || p.Message != c.Message) {
+			r.EventRecorder.Event(&eurekaApp, eventType, eventReasonConflict, c.Message)
+		}
+
+		if err := r.updateStatus(ctx, &eurekaApp, previous); err != nil {
 			log.Error(err, "unable to update eureka application status")
 			return ctrl.Result{}, err
 		}
 	}
 
+	// re-queue at the next maintenance window boundary, expiry or end of hysteresis so they are applied on time
+	if requeueAfter := eurekahandler.RequeueAfter(&eurekaApp, time.Now()); requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// updateStatus writes the status only when the reconcile changed it, so no-op reconciles don't trigger any event
+func (r *EurekaApplicationReconciler) updateStatus(
+	ctx context.Context,
+	eurekaApp *discoveryv1.EurekaApplication,
+	previous *discoveryv1.EurekaApplicationStatus,
+) error {
+	eurekaApp.Status.ObservedGeneration = eurekaApp.Generation
+	eurekaApp.Status.LastReconcileTime = previous.LastReconcileTime
+	if equality.Semantic.DeepEqual(previous, &eurekaApp.Status) {
+		return nil
+	}
+
+	eurekaApp.Status.LastReconcileTime = &metav1.Time{Time: time.Now()}
+	r.Log.Info("updating EurekaApplication status", "eurekaapplication", client.ObjectKeyFromObject(eurekaApp))
+
+	return r.Status().Update(ctx, eurekaApp)
+}
+
+// ingressApplications maps an ingress to the applications referencing it, so they are registered again on changes
+func (r *EurekaApplicationReconciler) ingressApplications(o client.Object) []reconcile.Request {
+	ingress, ok := o.(*networkingv1.Ingress)
+	if !ok {
+		return nil
+	}
+
+	var apps discoveryv1.EurekaApplicationList
+	if err := r.List(context.Background(), &apps, client.InNamespace(ingress.Namespace)); err != nil {
+		r.Log.Error(err, "unable to list eureka applications", "namespace", ingress.Namespace)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range apps.Items {
+		if eurekahandler.ReferencesIngress(&apps.Items[i], ingress) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&apps.Items[i])})
+		}
+	}
+
+	return requests
+}
+
+// endpointsApplications maps endpoints to the applications whose status follows them, so their instances go DOWN
+// or back UP on time
+func (r *EurekaApplicationReconciler) endpointsApplications(o client.Object) []reconcile.Request {
+	ctx := context.Background()
+
+	var apps discoveryv1.EurekaApplicationList
+	if err := r.List(ctx, &apps, client.InNamespace(o.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list eureka applications", "namespace", o.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range apps.Items {
+		if apps.Items[i].Spec.StatusFromEndpoints == nil {
+			continue
+		}
+
+		ingresses, err := eurekahandler.Ingresses(ctx, r.Client, &apps.Items[i])
+		if err != nil {
+			// the resource reports the error itself
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&apps.Items[i])})
+			continue
+		}
+
+		for _, service := range eurekahandler.BackendServices(ingresses) {
+			if service == o.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&apps.Items[i])})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// namespaceApplications re-queues the resources of the namespace of changed objects, i.e EurekaApplicationDefaults,
+// or every resource for changed cluster-scoped objects, i.e ClusterEurekaApplicationDefaults and policies
+func (r *EurekaApplicationReconciler) namespaceApplications(o client.Object) []reconcile.Request {
+	return r.applicationsOf(o.GetNamespace())
+}
+
+// namespaceObjectApplications re-queues the resources of changed namespaces
+func (r *EurekaApplicationReconciler) namespaceObjectApplications(o client.Object) []reconcile.Request {
+	return r.applicationsOf(o.GetName())
+}
+
+func (r *EurekaApplicationReconciler) applicationsOf(namespace string) []reconcile.Request {
+	var apps discoveryv1.EurekaApplicationList
+	if err := r.List(context.Background(), &apps, client.InNamespace(namespace)); err != nil {
+		r.Log.Error(err, "unable to list eureka applications", "namespace", namespace)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(apps.Items))
+	for i := range apps.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&apps.Items[i])})
+	}
+
+	return requests
+}
+
+// readinessChangedPredicate filters the endpoints updates that don't change whether they have ready addresses
+func readinessChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			previous, ok := e.ObjectOld.(*v1.Endpoints)
+			if !ok {
+				return true
+			}
+
+			endpoints, ok := e.ObjectNew.(*v1.Endpoints)
+			if !ok {
+				return true
+			}
+
+			return eurekahandler.HasReadyAddresses(previous) != eurekahandler.HasReadyAddresses(endpoints)
+		},
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *EurekaApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// resources losing or contending for instances are re-queued to refresh their Conflict condition,
+	// and resources of unfrozen environments to catch up
+	conflicts := make(chan event.GenericEvent)
+	r.EurekaHandler.EurekaSyncer.OnChange(func(resourceName string) {
+		namespace, name, err := cache.SplitMetaNamespaceKey(resourceName)
+		if err != nil {
+			r.Log.Error(err, "unable to re-queue resource", "resource", resourceName)
+			return
+		}
+
+		conflicts <- event.GenericEvent{
+			Object: &discoveryv1.EurekaApplication{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}},
+		}
+	})
+
+	r.rateLimiter = newCategoryRateLimiter()
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&discoveryv1.EurekaApplication{}).
+		Watches(&source.Channel{Source: conflicts}, &handler.EnqueueRequestForObject{}).
+		Watches(&source.Kind{Type: &networkingv1.Ingress{}}, handler.EnqueueRequestsFromMapFunc(r.ingressApplications)).
+		Watches(&source.Kind{Type: &v1.Endpoints{}}, handler.EnqueueRequestsFromMapFunc(r.endpointsApplications),
+			builder.WithPredicates(readinessChangedPredicate())).
+		Watches(&source.Kind{Type: &discoveryv1.EurekaApplicationDefaults{}}, handler.EnqueueRequestsFromMapFunc(r.namespaceApplications)).
+		Watches(&source.Kind{Type: &discoveryv1.ClusterEurekaApplicationDefaults{}}, handler.EnqueueRequestsFromMapFunc(r.namespaceApplications)).
+		Watches(&source.Kind{Type: &discoveryv1.EurekaEnvironmentPolicy{}}, handler.EnqueueRequestsFromMapFunc(r.namespaceApplications)).
+		Watches(&source.Kind{Type: &v1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.namespaceObjectApplications),
+			builder.WithPredicates(predicate.Or(predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		WithOptions(controller.Options{RateLimiter: r.rateLimiter}).
 		Complete(r)
 }
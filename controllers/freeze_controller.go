@@ -0,0 +1,125 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	eurek8ssyncer "github.com/eurek8s/controller/internal/eureka/sync"
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
+)
+
+const (
+	// FreezeKeyEnvironments lists the frozen environments, separated by commas, or * to freeze them all
+	FreezeKeyEnvironments = "environments"
+	// FreezeKeyPauseHeartbeats pauses the heartbeats of the frozen environments when "true"
+	FreezeKeyPauseHeartbeats = "pauseHeartbeats"
+	// FreezeKeyAllowMassDeregistrations lists the environments whose mass deregistration guard is bypassed
+	FreezeKeyAllowMassDeregistrations = "allowMassDeregistrations"
+
+	freezeSource = "configmap"
+)
+
+// FreezeReconciler freezes the environments listed in the freeze ConfigMap, lifting the freeze once it's removed.
+// The ConfigMap also lists the environments allowed to bypass their mass deregistration guard.
+type FreezeReconciler struct {
+	client.Client
+	Log logr.Logger
+
+	// Key of the freeze ConfigMap
+	Key          types.NamespacedName
+	EurekaSyncer *eurek8ssyncer.Synchronizer
+
+	// cache of the freeze ConfigMap only
+	cache cache.Cache
+}
+
+//+kubebuilder:rbac:groups="",namespace=system,resources=configmaps,verbs=get;list;watch
+
+func (r *FreezeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var cm v1.ConfigMap
+	if err := r.cache.Get(ctx, r.Key, &cm); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+
+		r.EurekaSyncer.SetFreeze(freezeSource, eurek8ssyncer.Freeze{})
+		r.EurekaSyncer.AllowMassDeregistrations(nil)
+		return ctrl.Result{}, nil
+	}
+
+	freeze := eurek8ssyncer.Freeze{
+		Environments:    splitEnvironments(cm.Data[FreezeKeyEnvironments]),
+		PauseHeartbeats: cm.Data[FreezeKeyPauseHeartbeats] == "true",
+	}
+	allowed := splitEnvironments(cm.Data[FreezeKeyAllowMassDeregistrations])
+
+	r.Log.Info("freeze updated", "environments", freeze.Environments, "pauseHeartbeats", freeze.PauseHeartbeats,
+		"allowMassDeregistrations", allowed)
+	r.EurekaSyncer.SetFreeze(freezeSource, freeze)
+	r.EurekaSyncer.AllowMassDeregistrations(allowed)
+
+	return ctrl.Result{}, nil
+}
+
+func splitEnvironments(value string) []string {
+	var environments []string
+	for _, e := range strings.Split(value, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			environments = append(environments, e)
+		}
+	}
+
+	return environments
+}
+
+// SetupWithManager sets up the controller with the Manager. The freeze ConfigMap is watched with a cache of its own,
+// so the ConfigMaps of the cluster are neither cached nor readable by the controller.
+func (r *FreezeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	c, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:    mgr.GetScheme(),
+		Mapper:    mgr.GetRESTMapper(),
+		Namespace: r.Key.Namespace,
+		SelectorsByObject: cache.SelectorsByObject{
+			&v1.ConfigMap{}: {Field: fields.OneTermEqualSelector("metadata.name", r.Key.Name)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := mgr.Add(c); err != nil {
+		return err
+	}
+	r.cache = c
+
+	ctl, err := controller.New("freeze", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	return ctl.Watch(source.NewKindWithCache(&v1.ConfigMap{}, c), &handler.EnqueueRequestForObject{})
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"github.com/go-logr/logr"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	AnnotationAppName         = "eurek8s.com/app-name"
+	AnnotationEnvironment     = "eurek8s.com/environment"
+	AnnotationZone            = "eurek8s.com/zone"
+	AnnotationHealthCheckPath = "eurek8s.com/healthcheck-path"
+	AnnotationStatusPath      = "eurek8s.com/status-path"
+	AnnotationHomePath        = "eurek8s.com/home-path"
+
+	eventReasonAlreadyExists = "AlreadyExists"
+)
+
+// IngressReconciler manages the EurekaApplication of every Ingress annotated with AnnotationAppName
+type IngressReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+
+	EventRecorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("ingress", req.NamespacedName)
+
+	var ingress networkingv1.Ingress
+	if err := r.Get(ctx, req.NamespacedName, &ingress); err != nil {
+		// the owned EurekaApplication is garbage collected along with the Ingress
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var eurekaApp discoveryv1.EurekaApplication
+	if err := r.Get(ctx, req.NamespacedName, &eurekaApp); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+	} else if !metav1.IsControlledBy(&eurekaApp, &ingress) {
+		if ingress.Annotations[AnnotationAppName] != "" {
+			message := fmt.Sprintf("EurekaApplication %s not managed by this ingress already exists", eurekaApp.Name)
+			r.EventRecorder.Event(&ingress, eventType, eventReasonAlreadyExists, message)
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if ingress.Annotations[AnnotationAppName] == "" || !ingress.DeletionTimestamp.IsZero() {
+		if eurekaApp.UID != "" {
+			log.Info("deleting EurekaApplication of ingress no longer annotated")
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, &eurekaApp))
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	eurekaApp = discoveryv1.EurekaApplication{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ingress.Namespace, Name: ingress.Name},
+	}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, &eurekaApp, func() error {
+		eurekaApp.Spec.AppName = ingress.Annotations[AnnotationAppName]
+		eurekaApp.Spec.IngressName = ingress.Name
+
+		// the fields without annotation are left as they are, i.e filled in by the defaulting webhook,
+		// so the EurekaApplication is not updated at every reconcile
+		for annotation, field := range map[string]*string{
+			AnnotationEnvironment:     &eurekaApp.Spec.Environment,
+			AnnotationZone:            &eurekaApp.Spec.Zone,
+			AnnotationHealthCheckPath: &eurekaApp.Spec.Paths.HealthCheck,
+			AnnotationStatusPath:      &eurekaApp.Spec.Paths.Status,
+			AnnotationHomePath:        &eurekaApp.Spec.Paths.Home,
+		} {
+			if value := ingress.Annotations[annotation]; value != "" {
+				*field = value
+			}
+		}
+
+		return controllerutil.SetControllerReference(&ingress, &eurekaApp, r.Scheme)
+	})
+	if err != nil {
+		log.Error(err, "unable to create or update EurekaApplication")
+		return ctrl.Result{}, err
+	}
+
+	if result != controllerutil.OperationResultNone {
+		log.Info("EurekaApplication reconciled", "operation", result)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IngressReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&networkingv1.Ingress{}).
+		Owns(&discoveryv1.EurekaApplication{}).
+		Complete(r)
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	eurekahandler "github.com/eurek8s/controller/internal/eureka/handler"
+	"k8s.io/client-go/util/workqueue"
+	"sync"
+	"time"
+)
+
+// categoryRateLimiter retries each resource with the backoff policy of the category of its last error
+type categoryRateLimiter struct {
+	mu         sync.Mutex
+	categories map[interface{}]eurekahandler.ErrorCategory
+	limiters   map[eurekahandler.ErrorCategory]workqueue.RateLimiter
+	fallback   workqueue.RateLimiter
+}
+
+func newCategoryRateLimiter() *categoryRateLimiter {
+	return &categoryRateLimiter{
+		categories: make(map[interface{}]eurekahandler.ErrorCategory),
+		limiters: map[eurekahandler.ErrorCategory]workqueue.RateLimiter{
+			// the referenced Ingress or Service is usually created shortly after
+			eurekahandler.CategoryNotFound: workqueue.NewItemExponentialFailureRateLimiter(5*time.Second, 5*time.Minute),
+			// the spec is re-queued on update, retrying is only useful for changes of the referenced objects
+			eurekahandler.CategoryInvalidSpec: workqueue.NewItemExponentialFailureRateLimiter(time.Minute, time.Hour),
+			// the resource is re-queued as well when the policies or the labels of its namespace change
+			eurekahandler.CategoryPolicyDenied:      workqueue.NewItemExponentialFailureRateLimiter(time.Minute, time.Hour),
+			eurekahandler.CategoryEurekaUnavailable: workqueue.NewItemExponentialFailureRateLimiter(time.Second, 2*time.Minute),
+			// the configuration only changes with a restart of the controller
+			eurekahandler.CategoryUnknownEnvironment: workqueue.NewItemExponentialFailureRateLimiter(10*time.Minute, time.Hour),
+			// held deregistrations wait for an override, which re-queues the resource when set by annotation
+			eurekahandler.CategoryDeregistrationHeld: workqueue.NewItemExponentialFailureRateLimiter(time.Minute, 10*time.Minute),
+		},
+		fallback: workqueue.DefaultControllerRateLimiter(),
+	}
+}
+
+// categorize sets the category of the last error of the item, used for its next retries
+func (r *categoryRateLimiter) categorize(item interface{}, category eurekahandler.ErrorCategory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if previous, ok := r.categories[item]; ok && previous != category {
+		r.limiter(previous).Forget(item)
+	}
+
+	r.categories[item] = category
+}
+
+func (r *categoryRateLimiter) When(item interface{}) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.limiter(r.categories[item]).When(item)
+}
+
+func (r *categoryRateLimiter) Forget(item interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, l := range r.limiters {
+		l.Forget(item)
+	}
+	r.fallback.Forget(item)
+
+	delete(r.categories, item)
+}
+
+func (r *categoryRateLimiter) NumRequeues(item interface{}) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.limiter(r.categories[item]).NumRequeues(item)
+}
+
+func (r *categoryRateLimiter) limiter(category eurekahandler.ErrorCategory) workqueue.RateLimiter {
+	if l, ok := r.limiters[category]; ok {
+		return l
+	}
+
+	return r.fallback
+}
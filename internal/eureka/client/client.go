@@ -1,13 +1,18 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/hudl/fargo"
 	"github.com/pkg/errors"
+	"net/http"
+	"net/url"
 )
 
 type EurekaClient struct {
 	connections map[string]fargo.EurekaConnection
+	limiters    map[string]*writeLimiter
 }
 
 func New(addresses map[string][]string) *EurekaClient {
@@ -16,7 +21,17 @@ func New(addresses map[string][]string) *EurekaClient {
 		connections[k] = fargo.NewConn(v...)
 	}
 
-	return &EurekaClient{connections: connections}
+	return &EurekaClient{connections: connections, limiters: make(map[string]*writeLimiter)}
+}
+
+// Environments returns the environments with a Eureka connection
+func (c *EurekaClient) Environments() []string {
+	var environments []string
+	for k := range c.connections {
+		environments = append(environments, k)
+	}
+
+	return environments
 }
 
 func (c *EurekaClient) call(
@@ -26,7 +41,7 @@ func (c *EurekaClient) call(
 ) error {
 	if conn, ok := c.connections[environment]; !ok {
 		return errors.New(fmt.Sprintf("cannot find eureka connection for environment \"%s\"", environment))
-	} else if err := f(conn, i); err != nil {
+	} else if err := f(conn, copyInstance(i)); err != nil {
 		statusCode, _ := fargo.HTTPResponseStatusCode(err)
 
 		return errors.Wrap(err, fmt.Sprintf("invalid status code received: %d", statusCode))
@@ -51,6 +66,47 @@ func (c *EurekaClient) RegisterInstance(environment string, i *fargo.Instance) e
 	)
 }
 
+// ReregisterInstance registers the instance, overwriting the registration if it already exists
+func (c *EurekaClient) ReregisterInstance(environment string, i *fargo.Instance) error {
+	return c.call(
+		environment,
+		i,
+		func(c fargo.EurekaConnection, i *fargo.Instance) error { return c.ReregisterInstance(i) },
+	)
+}
+
+// ReregisterAsgInstance registers the instance as part of the ASG, fargo has no support for asgName
+func (c *EurekaClient) ReregisterAsgInstance(environment string, i *fargo.Instance, asgName string) error {
+	if asgName == "" {
+		return c.ReregisterInstance(environment, i)
+	}
+
+	return c.call(
+		environment,
+		i,
+		func(c fargo.EurekaConnection, i *fargo.Instance) error {
+			body, err := json.Marshal(&fargo.RegisterInstanceJson{Instance: i})
+			if err != nil {
+				return err
+			}
+
+			var registration map[string]map[string]interface{}
+			if err := json.Unmarshal(body, &registration); err != nil {
+				return err
+			}
+			registration["instance"]["asgName"] = asgName
+
+			if body, err = json.Marshal(registration); err != nil {
+				return err
+			}
+
+			reqURL := fmt.Sprintf("%s/%s/%s", c.SelectServiceURL(), fargo.EurekaURLSlugs["Apps"], i.App)
+
+			return doRequestWithBody(http.MethodPost, reqURL, body)
+		},
+	)
+}
+
 func (c *EurekaClient) DeregisterInstance(environment string, i *fargo.Instance) error {
 	return c.call(
 		environment,
@@ -59,6 +115,80 @@ func (c *EurekaClient) DeregisterInstance(environment string, i *fargo.Instance)
 	)
 }
 
+func (c *EurekaClient) UpdateInstanceStatus(environment string, i *fargo.Instance, status fargo.StatusType) error {
+	return c.call(
+		environment,
+		i,
+		func(c fargo.EurekaConnection, i *fargo.Instance) error { return c.UpdateInstanceStatus(i, status) },
+	)
+}
+
+// RemoveStatusOverride drops the status override of the instance, fargo has no support for it
+func (c *EurekaClient) RemoveStatusOverride(environment string, i *fargo.Instance) error {
+	return c.call(
+		environment,
+		i,
+		func(c fargo.EurekaConnection, i *fargo.Instance) error {
+			reqURL := fmt.Sprintf("%s/%s/%s/%s/status?value=%s",
+				c.SelectServiceURL(), fargo.EurekaURLSlugs["Apps"], i.App, i.Id(), fargo.UP)
+
+			return doRequest(http.MethodDelete, reqURL)
+		},
+	)
+}
+
+// UpdateAsgStatus enables or disables the traffic of every instance of the ASG, fargo has no support for it
+func (c *EurekaClient) UpdateAsgStatus(environment, asgName, status string) error {
+	conn, ok := c.connections[environment]
+	if !ok {
+		return errors.New(fmt.Sprintf("cannot find eureka connection for environment \"%s\"", environment))
+	}
+
+	reqURL := fmt.Sprintf("%s/asg/%s/status?value=%s", conn.SelectServiceURL(), url.PathEscape(asgName), url.QueryEscape(status))
+
+	return doRequest(http.MethodPut, reqURL)
+}
+
+// copyInstance keeps the instance as built by the caller, fargo overwrites registered instances with the values read back from Eureka
+func copyInstance(i *fargo.Instance) *fargo.Instance {
+	c := *i
+	return &c
+}
+
+// IsNotFound reports whether Eureka answered the call with a 404, i.e the instance is not registered
+func IsNotFound(err error) bool {
+	statusCode, _ := fargo.HTTPResponseStatusCode(errors.Cause(err))
+
+	return statusCode == http.StatusNotFound
+}
+
+func doRequest(method, reqURL string) error {
+	return doRequestWithBody(method, reqURL, nil)
+}
+
+func doRequestWithBody(method, reqURL string, body []byte) error {
+	req, err := http.NewRequest(method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := fargo.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.New(fmt.Sprintf("invalid status code received: %d", resp.StatusCode))
+	}
+
+	return nil
+}
+
 func (c *EurekaClient) GetApp(environment, appName string) (*fargo.Application, error) {
 	if conn, ok := c.connections[environment]; !ok {
 		return nil, errors.New(fmt.Sprintf("cannot find eureka connection for environment \"%s\"", environment))
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Priority orders the writes waiting for the rate limit of their environment
+type Priority string
+
+const (
+	// PriorityHigh is served before any waiting PriorityLow write, i.e deregistrations
+	PriorityHigh Priority = "high"
+	// PriorityLow is registrations and status updates
+	PriorityLow Priority = "low"
+)
+
+var writeQueueDepth = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "eurek8s_write_queue_depth",
+		Help: "Number of Eureka writes waiting for the rate limit of the environment",
+	},
+	[]string{"environment", "priority"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(writeQueueDepth)
+}
+
+// writeLimiter is a token bucket handing its tokens to the high priority writes first
+type writeLimiter struct {
+	environment string
+	limiter     *rate.Limiter
+	high        chan struct{}
+	low         chan struct{}
+}
+
+// SetRateLimit limits the writes to the environment to writesPerSecond, allowing bursts of burst writes.
+// The writes don't wait by themselves, callers wait with Wait first. Heartbeats are never limited.
+func (c *EurekaClient) SetRateLimit(environment string, writesPerSecond float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	l := &writeLimiter{
+		environment: environment,
+		limiter:     rate.NewLimiter(rate.Limit(writesPerSecond), burst),
+		high:        make(chan struct{}),
+		low:         make(chan struct{}),
+	}
+	c.limiters[environment] = l
+
+	go l.dispatch()
+}
+
+// Wait blocks until a write is allowed by the rate limit of the environment, if any. Callers release
+// their locks while waiting, so the writes queue here and are served by priority.
+func (c *EurekaClient) Wait(environment string, p Priority) {
+	l, ok := c.limiters[environment]
+	if !ok {
+		return
+	}
+
+	queue := l.low
+	if p == PriorityHigh {
+		queue = l.high
+	}
+
+	depth := writeQueueDepth.WithLabelValues(environment, string(p))
+	depth.Inc()
+	queue <- struct{}{}
+	depth.Dec()
+}
+
+func (l *writeLimiter) dispatch() {
+	for {
+		_ = l.limiter.Wait(context.Background())
+
+		select {
+		case <-l.high:
+			continue
+		default:
+		}
+
+		select {
+		case <-l.high:
+		case <-l.low:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWaitServesHighPriorityFirst(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued []Priority
+		want   []Priority
+	}{
+		{
+			name:   "high queued after low",
+			queued: []Priority{PriorityLow, PriorityHigh},
+			want:   []Priority{PriorityHigh, PriorityLow},
+		},
+		{
+			name:   "high queued before low",
+			queued: []Priority{PriorityHigh, PriorityLow},
+			want:   []Priority{PriorityHigh, PriorityLow},
+		},
+		{
+			name:   "high queued after several low",
+			queued: []Priority{PriorityLow, PriorityLow, PriorityHigh},
+			want:   []Priority{PriorityHigh, PriorityLow, PriorityLow},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(map[string][]string{"qa": {"http://localhost"}})
+			c.SetRateLimit("qa", 5, 1)
+
+			// the burst is spent, the next writes queue until the next token
+			c.Wait("qa", PriorityLow)
+
+			served := make(chan Priority, len(tt.queued))
+			for _, p := range tt.queued {
+				go func(p Priority) {
+					c.Wait("qa", p)
+					served <- p
+				}(p)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			var got []Priority
+			for range tt.queued {
+				select {
+				case p := <-served:
+					got = append(got, p)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("writes not served, got %v", got)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("served %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitWithoutRateLimit(t *testing.T) {
+	c := New(map[string][]string{"qa": {"http://localhost"}})
+
+	done := make(chan struct{})
+	go func() {
+		c.Wait("qa", PriorityLow)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("write waiting for an environment without rate limit")
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config maps every environment key to the settings of its Eureka cluster
+type Config map[string]Environment
+
+type Environment struct {
+	// Addresses of the Eureka instances of the cluster
+	Addresses []string `json:"addresses"`
+
+	// DataCenter used to register the instances of this environment
+	DataCenter DataCenter `json:"dataCenter,omitempty"`
+
+	// Frozen queues the registrations and deregistrations of this environment until it's unfrozen
+	Frozen bool `json:"frozen,omitempty"`
+
+	// PauseHeartbeats pauses the heartbeats as well while the environment is frozen
+	PauseHeartbeats bool `json:"pauseHeartbeats,omitempty"`
+
+	// DeregistrationGuard holds back the deregistrations once too many happen within a window
+	DeregistrationGuard *DeregistrationGuard `json:"deregistrationGuard,omitempty"`
+
+	// RateLimit limits the registrations, deregistrations and status updates sent to the cluster
+	RateLimit *RateLimit `json:"rateLimit,omitempty"`
+}
+
+// RateLimit is a token bucket refilled with WritesPerSecond tokens and holding up to Burst tokens
+type RateLimit struct {
+	WritesPerSecond float64 `json:"writesPerSecond"`
+	Burst           int     `json:"burst,omitempty"`
+}
+
+// DeregistrationGuard limits the deregistrations within a window, zero limits being ignored
+type DeregistrationGuard struct {
+	MaxInstances int `json:"maxInstances,omitempty"`
+	MaxPercent   int `json:"maxPercent,omitempty"`
+	// MinInstances deregistrations are allowed regardless of MaxPercent, 3 by default
+	MinInstances int `json:"minInstances,omitempty"`
+	// Window is a duration such as "5m", 5 minutes by default
+	Window string `json:"window,omitempty"`
+}
+
+// DataCenter holds the data center type and the Amazon metadata defaults
+type DataCenter struct {
+	// Name of the data center type, MyOwn (default) or Amazon
+	Name             string `json:"name,omitempty"`
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+	InstanceType     string `json:"instanceType,omitempty"`
+	PublicHostname   string `json:"publicHostname,omitempty"`
+	PublicIpv4       string `json:"publicIpv4,omitempty"`
+	LocalHostname    string `json:"localHostname,omitempty"`
+	LocalIpv4        string `json:"localIpv4,omitempty"`
+}
+
+// UnmarshalJSON accepts both the environment object and the plain list of addresses
+func (e *Environment) UnmarshalJSON(b []byte) error {
+	var addresses []string
+	if err := json.Unmarshal(b, &addresses); err == nil {
+		*e = Environment{Addresses: addresses}
+		return nil
+	}
+
+	type plain Environment
+	return json.Unmarshal(b, (*plain)(e))
+}
+
+func Parse(raw string) (Config, error) {
+	config := make(Config)
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, err
+	}
+
+	if len(config) == 0 {
+		return nil, errors.New("no environment configured")
+	}
+
+	for key, environment := range config {
+		if len(environment.Addresses) == 0 {
+			return nil, errors.New(fmt.Sprintf("no addresses configured for environment \"%s\"", key))
+		}
+
+		if r := environment.RateLimit; r != nil && r.WritesPerSecond <= 0 {
+			return nil, errors.New(fmt.Sprintf("writesPerSecond must be positive for environment \"%s\"", key))
+		}
+
+		if g := environment.DeregistrationGuard; g != nil && g.Window != "" {
+			if _, err := time.ParseDuration(g.Window); err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid deregistration guard window for environment \"%s\": %s", key, err))
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// Addresses returns the Eureka addresses of every environment
+func (c Config) Addresses() map[string][]string {
+	addresses := make(map[string][]string, len(c))
+	for k, v := range c {
+		addresses[k] = v.Addresses
+	}
+
+	return addresses
+}
+
+// WindowDuration returns the parsed window of the guard, zero when unset
+func (g DeregistrationGuard) WindowDuration() time.Duration {
+	d, _ := time.ParseDuration(g.Window)
+	return d
+}
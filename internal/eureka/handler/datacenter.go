@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"github.com/eurek8s/controller/internal/eureka/config"
+	"github.com/hudl/fargo"
+	"github.com/pkg/errors"
+	networkingv1 "k8s.io/api/networking/v1"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getIPAddress returns the IP address to register for the host according to the address source
+func getIPAddress(
+	ctx context.Context,
+	source discoveryv1.AddressSource,
+	host string,
+	ingress networkingv1.Ingress,
+) (string, error) {
+	switch source {
+	case discoveryv1.AddressSourceResolve:
+		return resolve(ctx, host)
+	case discoveryv1.AddressSourceLoadBalancer:
+		for _, lb := range ingress.Status.LoadBalancer.Ingress {
+			if lb.IP != "" {
+				return lb.IP, nil
+			} else if lb.Hostname != "" {
+				return resolve(ctx, lb.Hostname)
+			}
+		}
+
+		return "", errors.New(fmt.Sprintf("ingress %s/%s has no load balancer address", ingress.Namespace, ingress.Name))
+	default:
+		return host, nil
+	}
+}
+
+// hasLoadBalancer returns whether the ingress status holds a load balancer address
+func hasLoadBalancer(ingress networkingv1.Ingress) bool {
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.IP != "" || lb.Hostname != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func resolve(ctx context.Context, host string) (string, error) {
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("unable to resolve host %s", host))
+	}
+
+	for _, address := range addresses {
+		if ip := address.IP.To4(); ip != nil {
+			return ip.String(), nil
+		}
+	}
+
+	if len(addresses) == 0 {
+		return "", errors.New(fmt.Sprintf("no address found for host %s", host))
+	}
+
+	return addresses[0].IP.String(), nil
+}
+
+// mergeDataCenter overrides the environment data center settings with the ones set in the spec
+func mergeDataCenter(dc config.DataCenter, spec *discoveryv1.DataCenterInfo) config.DataCenter {
+	if spec == nil {
+		return dc
+	}
+
+	override := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+
+	override(&dc.Name, spec.Name)
+	override(&dc.AvailabilityZone, spec.AvailabilityZone)
+	override(&dc.InstanceType, spec.InstanceType)
+	override(&dc.PublicHostname, spec.PublicHostname)
+	override(&dc.PublicIpv4, spec.PublicIpv4)
+	override(&dc.LocalHostname, spec.LocalHostname)
+	override(&dc.LocalIpv4, spec.LocalIpv4)
+
+	return dc
+}
+
+// getDataCenterInfo builds the data center info of an instance, filling the Amazon metadata
+// that is not configured from the instance itself and the labels of its backend node
+func getDataCenterInfo(
+	ctx context.Context,
+	c client.Client,
+	dc config.DataCenter,
+	i *fargo.Instance,
+	namespace string,
+	serviceName string,
+) (fargo.DataCenterInfo, error) {
+	if dc.Name != fargo.Amazon {
+		return fargo.DataCenterInfo{Name: fargo.MyOwn}, nil
+	}
+
+	valueOr := func(value, fallback string) string {
+		if value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	metadata := fargo.AmazonMetadataType{
+		InstanceID:       i.InstanceId,
+		HostName:         i.HostName,
+		AvailabilityZone: dc.AvailabilityZone,
+		InstanceType:     dc.InstanceType,
+		PublicHostname:   valueOr(dc.PublicHostname, i.HostName),
+		PublicIpv4:       valueOr(dc.PublicIpv4, i.IPAddr),
+		LocalHostname:    valueOr(dc.LocalHostname, i.HostName),
+		LocalIpv4:        valueOr(dc.LocalIpv4, i.IPAddr),
+	}
+
+	if metadata.AvailabilityZone == "" || metadata.InstanceType == "" {
+		nodes, err := getBackendNodes(ctx, c, namespace, serviceName)
+		if err != nil {
+			return fargo.DataCenterInfo{}, err
+		}
+
+		if len(nodes) > 0 {
+			metadata.AvailabilityZone = valueOr(metadata.AvailabilityZone, majorityZone(nodes))
+			metadata.InstanceType = valueOr(metadata.InstanceType, nodes[0].Labels[instanceTypeLabel])
+		}
+	}
+
+	return fargo.DataCenterInfo{Name: fargo.Amazon, Metadata: metadata}, nil
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"context"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+// DefaultPath is the path of the home, status and health check pages left empty
+const DefaultPath = "/"
+
+// NamespaceEnvironment is the annotation and the label of the namespaces holding the environment of their applications
+type NamespaceEnvironment struct {
+	Annotation string
+	Label      string
+}
+
+// environment returns the environment held by the namespace, the annotation taking precedence over the label
+func (n NamespaceEnvironment) environment(ns *v1.Namespace) string {
+	if n.Annotation != "" && ns.Annotations[n.Annotation] != "" {
+		return ns.Annotations[n.Annotation]
+	}
+
+	if n.Label != "" {
+		return ns.Labels[n.Label]
+	}
+
+	return ""
+}
+
+// EffectiveSpec returns the spec of the application with its empty fields filled from the EurekaApplicationDefaults
+// of its namespace first, then the environment held by the namespace, the ClusterEurekaApplicationDefaults and
+// the built-in defaults. Several defaults of the same kind are applied in name order.
+func EffectiveSpec(
+	ctx context.Context,
+	c client.Reader,
+	app *discoveryv1.EurekaApplication,
+	namespaceEnvironment NamespaceEnvironment,
+) (*discoveryv1.EurekaApplicationSpec, error) {
+	spec, err := resolveDefaults(ctx, c, app, namespaceEnvironment)
+	if err != nil {
+		return nil, err
+	}
+
+	applyDefaults(spec, builtinDefaults(spec))
+
+	return spec, nil
+}
+
+// ApplyBuiltinDefaults fills the empty fields of the spec left empty by every defaults resource with the built-in
+// defaults. The values of the defaults resources and of the namespace are never written into the spec, they are
+// resolved at every reconcile. Neither is the environment when it is held by the namespaces, so relabeling a
+// namespace moves its applications.
+func ApplyBuiltinDefaults(
+	ctx context.Context,
+	c client.Reader,
+	app *discoveryv1.EurekaApplication,
+	namespaceEnvironment NamespaceEnvironment,
+) error {
+	resolved, err := resolveDefaults(ctx, c, app, namespaceEnvironment)
+	if err != nil {
+		return err
+	}
+
+	builtin := builtinDefaults(resolved)
+	if resolved.Environment != "" || namespaceEnvironment != (NamespaceEnvironment{}) {
+		builtin.Environment = ""
+	}
+	if resolved.Zone != "" {
+		builtin.Zone = ""
+	}
+	if resolved.Paths.Home != "" {
+		builtin.Paths.Home = ""
+	}
+	if resolved.Paths.Status != "" {
+		builtin.Paths.Status = ""
+	}
+	if resolved.Paths.HealthCheck != "" {
+		builtin.Paths.HealthCheck = ""
+	}
+	applyDefaults(&app.Spec, builtin)
+
+	return nil
+}
+
+// resolveDefaults returns the spec of the application with the defaults resources and the environment of the
+// namespace applied, and its app name template executed
+func resolveDefaults(
+	ctx context.Context,
+	c client.Reader,
+	app *discoveryv1.EurekaApplication,
+	namespaceEnvironment NamespaceEnvironment,
+) (*discoveryv1.EurekaApplicationSpec, error) {
+	spec := app.Spec.DeepCopy()
+
+	var defaults discoveryv1.EurekaApplicationDefaultsList
+	if err := c.List(ctx, &defaults, client.InNamespace(app.Namespace)); err != nil {
+		return nil, err
+	}
+	sort.Slice(defaults.Items, func(i, j int) bool { return defaults.Items[i].Name < defaults.Items[j].Name })
+	for _, d := range defaults.Items {
+		applyDefaults(spec, &d.Spec)
+	}
+
+	var ns v1.Namespace
+	if namespaceEnvironment != (NamespaceEnvironment{}) || isTemplate(spec.AppName) {
+		if err := c.Get(ctx, types.NamespacedName{Name: app.Namespace}, &ns); err != nil {
+			return nil, err
+		}
+	}
+
+	if namespaceEnvironment != (NamespaceEnvironment{}) {
+		applyDefaults(spec, &discoveryv1.EurekaApplicationDefaultsSpec{Environment: namespaceEnvironment.environment(&ns)})
+	}
+
+	if isTemplate(spec.AppName) {
+		appName, err := executeAppName(spec.AppName, app, &ns)
+		if err != nil {
+			return nil, err
+		}
+		spec.AppName = appName
+	}
+
+	var clusterDefaults discoveryv1.ClusterEurekaApplicationDefaultsList
+	if err := c.List(ctx, &clusterDefaults); err != nil {
+		return nil, err
+	}
+	sort.Slice(clusterDefaults.Items, func(i, j int) bool {
+		return clusterDefaults.Items[i].Name < clusterDefaults.Items[j].Name
+	})
+	for _, d := range clusterDefaults.Items {
+		applyDefaults(spec, &d.Spec)
+	}
+
+	return spec, nil
+}
+
+// builtinDefaults returns the defaults compiled into the controller
+func builtinDefaults(spec *discoveryv1.EurekaApplicationSpec) *discoveryv1.EurekaApplicationDefaultsSpec {
+	builtin := &discoveryv1.EurekaApplicationDefaultsSpec{
+		Environment: DefaultEnvironment,
+		Zone:        DefaultZone,
+		Paths:       discoveryv1.EurekaApplicationPaths{Home: DefaultPath},
+	}
+	// paths left empty are derived from the probes of the workload
+	if spec.PathsFromProbes == nil {
+		builtin.Paths.Status = DefaultPath
+		builtin.Paths.HealthCheck = DefaultPath
+	}
+
+	return builtin
+}
+
+// applyDefaults fills the empty fields of the spec, metadata being merged key by key
+func applyDefaults(spec *discoveryv1.EurekaApplicationSpec, d *discoveryv1.EurekaApplicationDefaultsSpec) {
+	defaultString := func(dst *string, value string) {
+		if *dst == "" {
+			*dst = value
+		}
+	}
+
+	if len(spec.Environments) == 0 {
+		defaultString(&spec.Environment, d.Environment)
+	}
+	defaultString(&spec.Zone, d.Zone)
+	defaultString(&spec.Paths.Home, d.Paths.Home)
+	defaultString(&spec.Paths.Status, d.Paths.Status)
+	defaultString(&spec.Paths.HealthCheck, d.Paths.HealthCheck)
+
+	for k, v := range d.Metadata {
+		if _, ok := spec.Metadata[k]; ok {
+			continue
+		}
+
+		if spec.Metadata == nil {
+			spec.Metadata = make(map[string]string)
+		}
+		spec.Metadata[k] = v
+	}
+
+	if spec.DataCenterInfo == nil && d.DataCenterInfo != nil {
+		spec.DataCenterInfo = d.DataCenterInfo.DeepCopy()
+	}
+
+	if spec.DeregistrationTimeout == nil && d.DeregistrationTimeout != nil {
+		spec.DeregistrationTimeout = d.DeregistrationTimeout.DeepCopy()
+	}
+}
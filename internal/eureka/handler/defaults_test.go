@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestApplyBuiltinDefaults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = discoveryv1.AddToScheme(scheme)
+
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"stage": "staging"}}}
+	builtinPaths := discoveryv1.EurekaApplicationPaths{Home: DefaultPath, Status: DefaultPath, HealthCheck: DefaultPath}
+
+	tests := []struct {
+		name                 string
+		objects              []client.Object
+		namespaceEnvironment NamespaceEnvironment
+		spec                 discoveryv1.EurekaApplicationSpec
+		want                 discoveryv1.EurekaApplicationSpec
+	}{
+		{
+			name: "no defaults",
+			spec: discoveryv1.EurekaApplicationSpec{AppName: "orders"},
+			want: discoveryv1.EurekaApplicationSpec{
+				AppName:     "orders",
+				Environment: DefaultEnvironment,
+				Zone:        DefaultZone,
+				Paths:       builtinPaths,
+			},
+		},
+		{
+			name: "set by the spec",
+			spec: discoveryv1.EurekaApplicationSpec{AppName: "orders", Environment: "production", Zone: "eu-west-1a"},
+			want: discoveryv1.EurekaApplicationSpec{
+				AppName:     "orders",
+				Environment: "production",
+				Zone:        "eu-west-1a",
+				Paths:       builtinPaths,
+			},
+		},
+		{
+			name: "set by the namespace defaults",
+			objects: []client.Object{&discoveryv1.EurekaApplicationDefaults{
+				ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "shop"},
+				Spec: discoveryv1.EurekaApplicationDefaultsSpec{
+					Environment: "staging",
+					Paths:       discoveryv1.EurekaApplicationPaths{HealthCheck: "/actuator/health"},
+					Metadata:    map[string]string{"team": "orders"},
+				},
+			}},
+			spec: discoveryv1.EurekaApplicationSpec{AppName: "orders"},
+			want: discoveryv1.EurekaApplicationSpec{
+				AppName: "orders",
+				Zone:    DefaultZone,
+				Paths:   discoveryv1.EurekaApplicationPaths{Home: DefaultPath, Status: DefaultPath},
+			},
+		},
+		{
+			name: "set by the cluster defaults",
+			objects: []client.Object{&discoveryv1.ClusterEurekaApplicationDefaults{
+				ObjectMeta: metav1.ObjectMeta{Name: "defaults"},
+				Spec:       discoveryv1.EurekaApplicationDefaultsSpec{Zone: "eu-west-1a"},
+			}},
+			spec: discoveryv1.EurekaApplicationSpec{AppName: "orders"},
+			want: discoveryv1.EurekaApplicationSpec{
+				AppName:     "orders",
+				Environment: DefaultEnvironment,
+				Paths:       builtinPaths,
+			},
+		},
+		{
+			name:                 "environment held by the namespaces",
+			namespaceEnvironment: NamespaceEnvironment{Label: "stage"},
+			spec:                 discoveryv1.EurekaApplicationSpec{AppName: "orders"},
+			want: discoveryv1.EurekaApplicationSpec{
+				AppName: "orders",
+				Zone:    DefaultZone,
+				Paths:   builtinPaths,
+			},
+		},
+		{
+			name:                 "namespace without environment",
+			namespaceEnvironment: NamespaceEnvironment{Label: "missing"},
+			spec:                 discoveryv1.EurekaApplicationSpec{AppName: "orders"},
+			want: discoveryv1.EurekaApplicationSpec{
+				AppName: "orders",
+				Zone:    DefaultZone,
+				Paths:   builtinPaths,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(namespace).WithObjects(tt.objects...).Build()
+			app := &discoveryv1.EurekaApplication{
+				ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+				Spec:       tt.spec,
+			}
+
+			if err := ApplyBuiltinDefaults(context.Background(), c, app, tt.namespaceEnvironment); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(app.Spec, tt.want) {
+				t.Errorf("spec = %+v, want %+v", app.Spec, tt.want)
+			}
+		})
+	}
+}
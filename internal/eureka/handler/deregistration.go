@@ -0,0 +1,141 @@
+package handler
+
+import (
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	eurek8ssyncer "github.com/eurek8s/controller/internal/eureka/sync"
+	"github.com/hudl/fargo"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"time"
+)
+
+const (
+	// AnnotationForceDelete removes the finalizer of a deleted resource without waiting for its deregistration
+	AnnotationForceDelete = "eurek8s.com/force-delete"
+
+	// AnnotationAllowMassDeregistration lets the deregistrations of the resource bypass the mass deregistration guard
+	AnnotationAllowMassDeregistration = "eurek8s.com/allow-mass-deregistration"
+
+	DefaultDeregistrationTimeout = 5 * time.Minute
+)
+
+// ErrDeregistrationPending is returned while instances of a deleted resource are still registered
+var ErrDeregistrationPending = errors.New("deregistration pending")
+
+// deregister deregisters the instances of a deleted resource, returning ErrDeregistrationPending
+// until every instance is confirmed deregistered, the timeout is reached or the deletion is forced.
+// The settings are read from the effective resource, the progress is reported in the status of spec.
+func (h *Handler) deregister(spec, effective *discoveryv1.EurekaApplication, resourceName string, now time.Time) error {
+	log := h.log.WithValues("resource", resourceName)
+
+	timeout := DefaultDeregistrationTimeout
+	if effective.Spec.DeregistrationTimeout != nil {
+		timeout = effective.Spec.DeregistrationTimeout.Duration
+	}
+
+	if spec.Annotations[AnnotationForceDelete] == "true" {
+		log.Info("force deleting resource, instances may be left registered")
+		h.EurekaSyncer.Deregister(resourceName)
+		return nil
+	}
+
+	if now.Sub(spec.DeletionTimestamp.Time) > timeout {
+		log.Error(errors.New("deregistration timed out"), "removing finalizer, instances may be left registered", "timeout", timeout)
+		h.EurekaSyncer.Deregister(resourceName)
+		return nil
+	}
+
+	err := h.EurekaSyncer.DeregisterSync(resourceName, registeredApplications(effective, resourceName))
+
+	d := spec.Status.Deregistration
+	if d == nil {
+		d = &discoveryv1.DeregistrationStatus{}
+		spec.Status.Deregistration = d
+	}
+
+	d.Attempts++
+	d.LastAttemptTime = &metav1.Time{Time: now}
+	d.LastError = ""
+	setStatus(spec, h.EurekaSyncer.Applications(resourceName))
+
+	if err != nil {
+		d.LastError = err.Error()
+		if errors.Is(err, eurek8ssyncer.ErrDeregistrationHeld) {
+			return heldError(err.Error())
+		}
+
+		return newError(CategoryEurekaUnavailable, errors.Wrap(ErrDeregistrationPending, err.Error()))
+	}
+
+	return nil
+}
+
+// deregisterDisabled deregisters the instances of a disabled or expired resource, including the ones registered
+// before a restart. They are reported in the status until Eureka confirms their deregistration.
+func (h *Handler) deregisterDisabled(spec, effective *discoveryv1.EurekaApplication, resourceName string) error {
+	err := h.EurekaSyncer.DeregisterSync(resourceName, registeredApplications(effective, resourceName))
+
+	apps := h.EurekaSyncer.Applications(resourceName)
+	setStatus(spec, apps)
+	setConflictCondition(spec, apps)
+
+	if err != nil {
+		if errors.Is(err, eurek8ssyncer.ErrDeregistrationHeld) {
+			return heldError(err.Error())
+		}
+
+		return newError(CategoryEurekaUnavailable, err)
+	}
+
+	return nil
+}
+
+// heldError reports deregistrations held back by the mass deregistration guard, along with how to proceed
+func heldError(message string) error {
+	return newError(CategoryDeregistrationHeld, errors.Errorf(
+		"%s, set the %s annotation to \"true\" to proceed", message, AnnotationAllowMassDeregistration))
+}
+
+// registeredApplications rebuilds the applications registered by the resource from its status,
+// so instances registered before a restart of the controller are deregistered as well
+func registeredApplications(spec *discoveryv1.EurekaApplication, resourceName string) []*eurek8ssyncer.Application {
+	defaultEnvironment := Environments(spec)[0]
+
+	apps := make(map[string]*eurek8ssyncer.Application)
+	var result []*eurek8ssyncer.Application
+	for _, s := range spec.Status.Instances {
+		environment := s.Environment
+		if environment == "" {
+			environment = defaultEnvironment
+		}
+
+		name := s.App
+		if name == "" {
+			name = spec.Spec.AppName
+		}
+
+		app, ok := apps[environment]
+		if !ok {
+			app = &eurek8ssyncer.Application{
+				ResourceName:      resourceName,
+				CreationTimestamp: spec.CreationTimestamp.Time,
+				Environment:       environment,
+				Name:              name,
+				Status:            fargo.StatusType(s.Status),
+			}
+			apps[environment] = app
+			result = append(result, app)
+		}
+
+		app.Instances = append(app.Instances, &fargo.Instance{
+			UniqueID: func(i fargo.Instance) string {
+				return strings.ToLower(i.InstanceId)
+			},
+			InstanceId: s.InstanceID,
+			App:        name,
+		})
+	}
+
+	return result
+}
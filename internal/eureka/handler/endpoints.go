@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultEndpointsHysteresis is the time the endpoints must stay ready before the instances go back UP
+	DefaultEndpointsHysteresis = 30 * time.Second
+
+	reasonEndpointsReady      = "EndpointsReady"
+	reasonEndpointsRecovering = "EndpointsRecovering"
+	reasonNoReadyEndpoints    = "NoReadyEndpoints"
+)
+
+// BackendServices returns the names of the services behind the rules of the ingresses
+func BackendServices(ingresses []networkingv1.Ingress) []string {
+	seen := make(map[string]bool)
+	var services []string
+	for _, ingress := range ingresses {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service == nil || seen[path.Backend.Service.Name] {
+					continue
+				}
+
+				seen[path.Backend.Service.Name] = true
+				services = append(services, path.Backend.Service.Name)
+			}
+		}
+	}
+
+	return services
+}
+
+// HasReadyAddresses returns whether the endpoints have at least one ready address
+func HasReadyAddresses(endpoints *v1.Endpoints) bool {
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setEndpointsReadyCondition reports whether the backend services have ready endpoints, for resources with
+// statusFromEndpoints only. Endpoints coming back are reported as recovering until the hysteresis elapsed.
+func setEndpointsReadyCondition(ctx context.Context, c client.Client, spec *discoveryv1.EurekaApplication, now time.Time) error {
+	if spec.Spec.StatusFromEndpoints == nil {
+		meta.RemoveStatusCondition(&spec.Status.Conditions, discoveryv1.ConditionTypeEndpointsReady)
+		return nil
+	}
+
+	ingresses, err := Ingresses(ctx, c, spec)
+	if err != nil {
+		return err
+	}
+
+	services := BackendServices(ingresses)
+	ready := false
+	for _, service := range services {
+		var endpoints v1.Endpoints
+		if err := c.Get(ctx, types.NamespacedName{Namespace: spec.Namespace, Name: service}, &endpoints); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			continue
+		}
+
+		if HasReadyAddresses(&endpoints) {
+			ready = true
+			break
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               discoveryv1.ConditionTypeEndpointsReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: spec.Generation,
+		Reason:             reasonNoReadyEndpoints,
+		Message:            fmt.Sprintf("No ready endpoints behind services %s, the instances are DOWN", strings.Join(services, ", ")),
+	}
+
+	if ready {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonEndpointsReady
+		condition.Message = "The backend services have ready endpoints"
+
+		// the transition time of the previous condition is kept while it stays True
+		previous := meta.FindStatusCondition(spec.Status.Conditions, discoveryv1.ConditionTypeEndpointsReady)
+		since := now
+		if previous != nil && previous.Status == metav1.ConditionTrue {
+			since = previous.LastTransitionTime.Time
+		}
+
+		recovering := previous != nil && (previous.Status == metav1.ConditionFalse || previous.Reason == reasonEndpointsRecovering)
+		if until := since.Add(endpointsHysteresis(spec)); recovering && now.Before(until) {
+			condition.Reason = reasonEndpointsRecovering
+			condition.Message = fmt.Sprintf("The backend services have ready endpoints, the instances go UP at %s", until.UTC().Format(time.RFC3339))
+		}
+	}
+
+	meta.SetStatusCondition(&spec.Status.Conditions, condition)
+
+	return nil
+}
+
+func endpointsHysteresis(spec *discoveryv1.EurekaApplication) time.Duration {
+	if h := spec.Spec.StatusFromEndpoints.Hysteresis; h != nil {
+		return h.Duration
+	}
+
+	return DefaultEndpointsHysteresis
+}
+
+// endpointsDown returns whether the instances are DOWN because of their backend endpoints
+func endpointsDown(spec *discoveryv1.EurekaApplication) bool {
+	if spec.Spec.StatusFromEndpoints == nil {
+		return false
+	}
+
+	condition := meta.FindStatusCondition(spec.Status.Conditions, discoveryv1.ConditionTypeEndpointsReady)
+	return condition != nil && (condition.Status == metav1.ConditionFalse || condition.Reason == reasonEndpointsRecovering)
+}
+
+// endpointsRecovery returns the time the instances go back UP once the endpoints recovered, nil if they are not recovering
+func endpointsRecovery(spec *discoveryv1.EurekaApplication) *time.Time {
+	if spec.Spec.StatusFromEndpoints == nil {
+		return nil
+	}
+
+	condition := meta.FindStatusCondition(spec.Status.Conditions, discoveryv1.ConditionTypeEndpointsReady)
+	if condition == nil || condition.Reason != reasonEndpointsRecovering {
+		return nil
+	}
+
+	t := condition.LastTransitionTime.Add(endpointsHysteresis(spec))
+	return &t
+}
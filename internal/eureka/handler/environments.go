@@ -0,0 +1,71 @@
+package handler
+
+import (
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+)
+
+// target is an environment the application is registered into, with its overrides applied
+type target struct {
+	environment string
+	zone        string
+	paths       discoveryv1.EurekaApplicationPaths
+	metadata    map[string]string
+}
+
+// Environments returns the environments the application is registered into
+func Environments(spec *discoveryv1.EurekaApplication) []string {
+	var environments []string
+	for _, t := range getTargets(spec) {
+		environments = append(environments, t.environment)
+	}
+
+	return environments
+}
+
+func getTargets(spec *discoveryv1.EurekaApplication) []target {
+	zone := spec.Spec.Zone
+	if zone == "" {
+		zone = DefaultZone
+	}
+
+	if len(spec.Spec.Environments) == 0 {
+		environment := spec.Spec.Environment
+		if environment == "" {
+			environment = DefaultEnvironment
+		}
+
+		return []target{{environment: environment, zone: zone, paths: spec.Spec.Paths, metadata: spec.Spec.Metadata}}
+	}
+
+	var targets []target
+	for _, e := range spec.Spec.Environments {
+		t := target{environment: e.Name, zone: zone, paths: spec.Spec.Paths, metadata: make(map[string]string)}
+
+		if e.Zone != "" {
+			t.zone = e.Zone
+		}
+
+		if e.Paths != nil {
+			override := func(dst *string, src string) {
+				if src != "" {
+					*dst = src
+				}
+			}
+
+			override(&t.paths.HealthCheck, e.Paths.HealthCheck)
+			override(&t.paths.Home, e.Paths.Home)
+			override(&t.paths.Status, e.Paths.Status)
+		}
+
+		for k, v := range spec.Spec.Metadata {
+			t.metadata[k] = v
+		}
+		for k, v := range e.Metadata {
+			t.metadata[k] = v
+		}
+
+		targets = append(targets, t)
+	}
+
+	return targets
+}
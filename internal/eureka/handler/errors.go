@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrorCategory classifies the errors of the handler, each category being retried with its own backoff.
+// Categories are used as event and condition reasons.
+type ErrorCategory string
+
+const (
+	// CategoryNotFound is an object referenced by the resource, i.e the Ingress or a Service, missing
+	CategoryNotFound ErrorCategory = "NotFound"
+	// CategoryInvalidSpec is a resource that cannot be registered until its spec is fixed
+	CategoryInvalidSpec ErrorCategory = "InvalidSpec"
+	// CategoryEurekaUnavailable is Eureka failing or unreachable
+	CategoryEurekaUnavailable ErrorCategory = "EurekaUnavailable"
+	// CategoryUnknownEnvironment is an environment missing from the configuration
+	CategoryUnknownEnvironment ErrorCategory = "UnknownEnvironment"
+	// CategoryDeregistrationHeld is deregistrations held back by the mass deregistration guard of an environment
+	CategoryDeregistrationHeld ErrorCategory = "DeregistrationHeld"
+	// CategoryPolicyDenied is an environment the namespace or app name is not allowed to register into
+	CategoryPolicyDenied ErrorCategory = "PolicyDenied"
+	// CategoryUnknown is any other error
+	CategoryUnknown ErrorCategory = "ReconcileError"
+)
+
+// Error is an error of the handler along with its category
+type Error struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(category ErrorCategory, err error) error {
+	return &Error{Category: category, Err: err}
+}
+
+func invalidSpec(format string, args ...interface{}) error {
+	return newError(CategoryInvalidSpec, fmt.Errorf(format, args...))
+}
+
+// Classify returns the category of an error returned by the handler
+func Classify(err error) ErrorCategory {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+
+	if apierrors.IsNotFound(err) {
+		return CategoryNotFound
+	}
+
+	return CategoryUnknown
+}
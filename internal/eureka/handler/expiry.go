@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"fmt"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const (
+	reasonExpired    = "Expired"
+	reasonNotExpired = "NotExpired"
+)
+
+// expiry returns the time the registration of the resource expires at, nil if it never expires
+func expiry(spec *discoveryv1.EurekaApplication) *time.Time {
+	var t *time.Time
+	if spec.Spec.TTL != nil {
+		ttl := spec.CreationTimestamp.Add(spec.Spec.TTL.Duration)
+		t = &ttl
+	}
+
+	if e := spec.Spec.ExpiresAt; e != nil && (t == nil || e.Time.Before(*t)) {
+		t = &e.Time
+	}
+
+	return t
+}
+
+func expired(spec *discoveryv1.EurekaApplication, now time.Time) bool {
+	t := expiry(spec)
+	return t != nil && !now.Before(*t)
+}
+
+// setExpiredCondition reports whether the registration expired, for resources with an expiry only
+func setExpiredCondition(spec *discoveryv1.EurekaApplication, now time.Time) {
+	t := expiry(spec)
+	if t == nil {
+		meta.RemoveStatusCondition(&spec.Status.Conditions, discoveryv1.ConditionTypeExpired)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               discoveryv1.ConditionTypeExpired,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: spec.Generation,
+		Reason:             reasonNotExpired,
+		Message:            fmt.Sprintf("The registration expires at %s", t.UTC().Format(time.RFC3339)),
+	}
+
+	if !now.Before(*t) {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonExpired
+		condition.Message = fmt.Sprintf("The registration expired at %s, the instances are deregistered", t.UTC().Format(time.RFC3339))
+	}
+
+	meta.SetStatusCondition(&spec.Status.Conditions, condition)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"github.com/eurek8s/controller/internal/eureka/config"
 	eurek8ssyncer "github.com/eurek8s/controller/internal/eureka/sync"
 	"github.com/eurek8s/controller/internal/eureka/util"
 	"github.com/go-logr/logr"
@@ -11,9 +12,12 @@ import (
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
+	"time"
 )
 
 const (
@@ -21,6 +25,7 @@ const (
 
 	NoZone      = "no-zone"
 	DefaultZone = "default-zone"
+	AutoZone    = "auto"
 
 	FinalizerName = "finalizers.eurekaapplication.discovery.eurek8s.com"
 
@@ -28,33 +33,57 @@ const (
 	protocolHttps = "https"
 
 	httpsPort = 443
+
+	reasonConflict   = "InstanceConflict"
+	reasonNoConflict = "NoConflict"
+
+	reasonRegistered = "Registered"
+	reasonDisabled   = "Disabled"
+	reasonFrozen     = "EnvironmentFrozen"
+	reasonNotFrozen  = "NotFrozen"
 )
 
 type Handler struct {
 	EurekaSyncer *eurek8ssyncer.Synchronizer
-	log          logr.Logger
+	// NamespaceEnvironment resolves the environment of the applications leaving it empty from their namespace
+	NamespaceEnvironment NamespaceEnvironment
+	environments         config.Config
+	log                  logr.Logger
 }
 
-func New(syncer *eurek8ssyncer.Synchronizer, log logr.Logger) *Handler {
-	return &Handler{EurekaSyncer: syncer, log: log}
+func New(syncer *eurek8ssyncer.Synchronizer, environments config.Config, log logr.Logger) *Handler {
+	return &Handler{EurekaSyncer: syncer, environments: environments, log: log}
 }
 
 type hostPort struct {
-	host string
-	port int32
+	host    string
+	port    int32
+	path    string
+	service string
+	ingress *networkingv1.Ingress
 }
 
-// TODO split ingress retrieval from eureka registering
+// Handle registers the resource into Eureka, errors are classified with Classify
 func (h *Handler) Handle(
 	ctx context.Context,
 	c client.Client,
 	spec *discoveryv1.EurekaApplication,
 	resourceName string,
 ) error {
-	environment := spec.Spec.Environment
-	if environment == "" {
-		environment = DefaultEnvironment
-	}
+	err := h.handle(ctx, c, spec, resourceName)
+	setRegisteredCondition(spec, err)
+
+	return err
+}
+
+// TODO split ingress retrieval from eureka registering
+func (h *Handler) handle(
+	ctx context.Context,
+	c client.Client,
+	spec *discoveryv1.EurekaApplication,
+	resourceName string,
+) error {
+	h.EurekaSyncer.AllowMassDeregistration(resourceName, spec.Annotations[AnnotationAllowMassDeregistration] == "true")
 
 	if spec.ObjectMeta.DeletionTimestamp.IsZero() {
 		if !util.ContainsString(spec.ObjectMeta.Finalizers, FinalizerName) {
@@ -64,10 +93,31 @@ func (h *Handler) Handle(
 				return err
 			}
 		}
-	} else {
+	}
+
+	// the defaults are resolved at every reconcile, so their changes apply to the existing applications
+	effectiveSpec, err := EffectiveSpec(ctx, c, spec, h.NamespaceEnvironment)
+	if err != nil {
+		// never block the deregistration of a deleted resource, its instances are known from the status
+		if spec.DeletionTimestamp.IsZero() {
+			return err
+		}
+
+		h.log.Error(err, "unable to resolve the effective spec, using the spec")
+		effectiveSpec = spec.Spec.DeepCopy()
+	}
+	spec.Status.EffectiveSpec = effectiveSpec
+	effective := spec.DeepCopy()
+	effective.Spec = *effectiveSpec
+
+	if !spec.ObjectMeta.DeletionTimestamp.IsZero() {
 		if util.ContainsString(spec.ObjectMeta.Finalizers, FinalizerName) {
+			if err := h.deregister(spec, effective, resourceName, time.Now()); err != nil {
+				return err
+			}
+
 			h.log.Info("Deregistering finalizer", "name", FinalizerName)
-			h.EurekaSyncer.Deregister(resourceName)
+			h.EurekaSyncer.AllowMassDeregistration(resourceName, false)
 
 			spec.ObjectMeta.Finalizers = util.RemoveString(spec.ObjectMeta.Finalizers, FinalizerName)
 			if err := c.Update(ctx, spec); err != nil {
@@ -78,27 +128,325 @@ func (h *Handler) Handle(
 		return nil
 	}
 
-	disabled := spec.Spec.Disabled
+	now := time.Now()
+	setExpiredCondition(spec, now)
+
+	// resources owned by an Ingress would be created again by it, they are only deregistered
+	deletable := spec.Spec.ExpirationPolicy == discoveryv1.ExpirationPolicyDelete && metav1.GetControllerOf(spec) == nil
+	if expired(spec, now) && deletable {
+		h.log.Info("deleting expired resource")
+		if err := c.Delete(ctx, spec); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		// the instances are deregistered by the reconcile of the deletion, the stale status is not written meanwhile
+		spec.DeletionTimestamp = &metav1.Time{Time: now}
+		return nil
+	}
+
+	disabled := spec.Spec.Disabled || expired(spec, now)
 
 	if disabled {
-		h.EurekaSyncer.Deregister(resourceName)
-	} else if app, err := getEurekaApplication(ctx, c, spec, environment, resourceName, h.log); err != nil {
-		return err
-	} else if err := h.EurekaSyncer.RegisterApplicationSync(app); err != nil {
+		return h.deregisterDisabled(spec, effective, resourceName)
+	}
+
+	if spec.Spec.AppName == "" {
+		return invalidSpec("appName is required")
+	}
+
+	if !hasIngressReference(spec) {
+		return invalidSpec("ingressName, ingresses or ingressSelector is required")
+	}
+
+	if err := setEndpointsReadyCondition(ctx, c, spec, now); err != nil {
 		return err
 	}
+	// the status of the instances follows the conditions
+	effective.Status.Conditions = spec.Status.Conditions
+
+	var targets []target
+	var environments []string
+	var firstErr error
+	for _, t := range getTargets(effective) {
+		// every target is checked before any instance leaves its environment. Unknown and denied environments are
+		// left, so a policy denying the resource is enforced, while a failing check keeps the resource registered
+		// where it is.
+		if err := h.checkEnvironment(ctx, c, spec.Namespace, effective.Spec.AppName, t.environment); err != nil {
+			if category := Classify(err); category != CategoryUnknownEnvironment && category != CategoryPolicyDenied {
+				h.log.Error(err, "unable to check environment, keeping the existing registrations", "environment", t.environment)
+				return err
+			}
 
-	return nil
+			h.log.Error(err, "unable to register application, leaving the environment", "environment", t.environment)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		targets = append(targets, t)
+		environments = append(environments, t.environment)
+	}
+
+	var apps []*eurek8ssyncer.Application
+
+	// instances leave the environments no longer targeted before joining the new ones, so an application
+	// moving between Eureka clusters is never registered in both
+	registered := make(map[string]bool)
+	for _, i := range spec.Status.Instances {
+		registered[i.Environment] = true
+	}
+
+	err = h.EurekaSyncer.RetainEnvironments(resourceName, environments, registeredApplications(effective, resourceName))
+	moving := err != nil
+	if moving {
+		h.log.Info("waiting for the instances to leave the previous environments", "error", err.Error())
+		if firstErr == nil {
+			firstErr = newError(CategoryEurekaUnavailable, err)
+		}
+	}
+
+	for _, t := range targets {
+		if moving && !registered[t.environment] {
+			continue
+		}
+
+		app, err := h.getEurekaApplication(ctx, c, effective, t, resourceName)
+		if err == nil {
+			if err = h.EurekaSyncer.RegisterApplicationSync(app); err != nil {
+				err = newError(CategoryEurekaUnavailable, err)
+			}
+		}
+
+		if err != nil {
+			// environments are registered independently, keep reporting what is already registered
+			h.log.Error(err, "unable to register application", "environment", t.environment)
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			app = h.EurekaSyncer.Get(resourceName, t.environment)
+		}
+
+		if app != nil {
+			apps = append(apps, app)
+		}
+	}
+
+	setStatus(spec, apps)
+	setConflictCondition(spec, apps)
+	h.setFrozenCondition(spec, environments)
+
+	if held := h.EurekaSyncer.HeldDeregistrations(resourceName); held > 0 && firstErr == nil {
+		firstErr = heldError(fmt.Sprintf("%d instance deregistrations held back by the mass deregistration guard", held))
+	}
+
+	return firstErr
+}
+
+// checkEnvironment returns an error when the environment is not configured or the resource is denied it by the policies
+func (h *Handler) checkEnvironment(ctx context.Context, c client.Client, namespace, appName, environment string) error {
+	if _, ok := h.environments[environment]; !ok {
+		return newError(CategoryUnknownEnvironment, fmt.Errorf("environment %s is not configured", environment))
+	}
+
+	return CheckPolicy(ctx, c, namespace, appName, environment)
+}
+
+// statusSeverity orders the instance status from the best to the worst, unknown ones being the worst
+var statusSeverity = map[discoveryv1.InstanceStatus]int{
+	discoveryv1.InstanceStatusUp:           1,
+	discoveryv1.InstanceStatusStarting:     2,
+	discoveryv1.InstanceStatusOutOfService: 3,
+	discoveryv1.InstanceStatusDown:         4,
+}
+
+// worstStatus returns the worse of both status, an empty one being ignored
+func worstStatus(a, b discoveryv1.InstanceStatus) discoveryv1.InstanceStatus {
+	severity := func(s discoveryv1.InstanceStatus) int {
+		if s == "" {
+			return 0
+		}
+		if v, ok := statusSeverity[s]; ok {
+			return v
+		}
+		return len(statusSeverity) + 1
+	}
+
+	if severity(b) > severity(a) {
+		return b
+	}
+
+	return a
+}
+
+// setStatus reflects the registered instances and their status into the resource status, the top-level status
+// being the worst one across the environments
+func setStatus(spec *discoveryv1.EurekaApplication, apps []*eurek8ssyncer.Application) {
+	spec.Status.Status = ""
+	spec.Status.Instances = nil
+	spec.Status.AsgStatus = appliedAsgStatus(apps)
+
+	for _, app := range apps {
+		status := discoveryv1.InstanceStatus(app.Status)
+		spec.Status.Status = worstStatus(spec.Status.Status, status)
+		for _, i := range app.Instances {
+			spec.Status.Instances = append(spec.Status.Instances, discoveryv1.EurekaInstanceStatus{
+				InstanceID:  i.InstanceId,
+				App:         app.Name,
+				Ingress:     app.Sources[i.InstanceId],
+				Environment: app.Environment,
+				Status:      status,
+			})
+		}
+	}
 }
 
+// appliedAsgStatus returns the status applied to the ASGs of the applications, empty unless applied in every environment
+func appliedAsgStatus(apps []*eurek8ssyncer.Application) discoveryv1.AsgStatus {
+	var status discoveryv1.AsgStatus
+	for idx, app := range apps {
+		// the status is not applied without ASG
+		applied := discoveryv1.AsgStatus(app.AsgStatus)
+		if len(app.AsgNames) == 0 {
+			applied = ""
+		}
+
+		if idx > 0 && applied != status {
+			return ""
+		}
+		status = applied
+	}
+
+	return status
+}
+
+// setConflictCondition reports the instances registered by other resources in the Conflict condition
+func setConflictCondition(spec *discoveryv1.EurekaApplication, apps []*eurek8ssyncer.Application) {
+	condition := metav1.Condition{
+		Type:               discoveryv1.ConditionTypeConflict,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: spec.Generation,
+		Reason:             reasonNoConflict,
+		Message:            "All instances are registered by this resource",
+	}
+
+	var messages []string
+	for _, app := range apps {
+		for _, c := range app.Conflicts {
+			messages = append(messages, fmt.Sprintf("instance %s is registered in %s by %s", c.InstanceId, app.Environment, c.Owner))
+		}
+	}
+
+	if len(messages) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonConflict
+		condition.Message = strings.Join(messages, ", ")
+	}
+
+	meta.SetStatusCondition(&spec.Status.Conditions, condition)
+}
+
+// setFrozenCondition reports the environments of the resource whose writes are queued by a freeze
+func (h *Handler) setFrozenCondition(spec *discoveryv1.EurekaApplication, environments []string) {
+	condition := metav1.Condition{
+		Type:               discoveryv1.ConditionTypeFrozen,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: spec.Generation,
+		Reason:             reasonNotFrozen,
+		Message:            "No environment is frozen",
+	}
+
+	var frozen []string
+	for _, environment := range environments {
+		if h.EurekaSyncer.Frozen(environment) {
+			frozen = append(frozen, environment)
+		}
+	}
+
+	if len(frozen) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reasonFrozen
+		condition.Message = fmt.Sprintf("Registrations are queued until the freeze of %s is lifted", strings.Join(frozen, ", "))
+	}
+
+	meta.SetStatusCondition(&spec.Status.Conditions, condition)
+}
+
+// setRegisteredCondition reports whether the instances are registered, or the category of the error preventing it
+func setRegisteredCondition(spec *discoveryv1.EurekaApplication, err error) {
+	condition := metav1.Condition{
+		Type:               discoveryv1.ConditionTypeRegistered,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: spec.Generation,
+		Reason:             reasonRegistered,
+		Message:            "All instances are registered",
+	}
+
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = string(Classify(err))
+		condition.Message = err.Error()
+	} else if spec.Spec.Disabled {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonDisabled
+		condition.Message = "The application is disabled"
+	} else if expired(spec, time.Now()) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonExpired
+		condition.Message = "The registration expired"
+	}
+
+	meta.SetStatusCondition(&spec.Status.Conditions, condition)
+}
+
+// getHostPorts returns the host and port of every rule of the ingresses. The paths of a host and port are merged
+// into their longest common prefix, so each one is registered once, from the first ingress exposing it.
 func getHostPorts(
 	ctx context.Context,
 	c client.Client,
-	ingress networkingv1.Ingress,
+	ingresses []networkingv1.Ingress,
+) ([]hostPort, error) {
+	var hostPorts []hostPort
+	index := make(map[string]int)
+	for idx := range ingresses {
+		hps, err := getIngressHostPorts(ctx, c, &ingresses[idx])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, hp := range hps {
+			key := fmt.Sprintf("%s:%d", strings.ToLower(hp.host), hp.port)
+			if i, ok := index[key]; ok {
+				hostPorts[i].path = commonPathPrefix(hostPorts[i].path, hp.path)
+				continue
+			}
+
+			index[key] = len(hostPorts)
+			hostPorts = append(hostPorts, hp)
+		}
+	}
+
+	return hostPorts, nil
+}
+
+func getIngressHostPorts(
+	ctx context.Context,
+	c client.Client,
+	ingress *networkingv1.Ingress,
 ) ([]hostPort, error) {
 	var hostPorts []hostPort
+	index := make(map[string]int)
 	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+
 		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service == nil {
+				continue
+			}
+
 			var port int32
 
 			if path.Backend.Service.Port.Name != "" {
@@ -109,7 +457,7 @@ func getHostPorts(
 				}
 
 				for _, sport := range service.Spec.Ports {
-					if sport.Name == path.Backend.Service.Name {
+					if sport.Name == path.Backend.Service.Port.Name {
 						port = sport.Port
 						break
 					}
@@ -118,96 +466,193 @@ func getHostPorts(
 				port = path.Backend.Service.Port.Number
 			}
 
-			hostPorts = append(hostPorts, hostPort{host: rule.Host, port: port})
+			prefix := pathPrefix(path)
+
+			key := fmt.Sprintf("%s:%d", strings.ToLower(rule.Host), port)
+			if idx, ok := index[key]; ok {
+				hostPorts[idx].path = commonPathPrefix(hostPorts[idx].path, prefix)
+				continue
+			}
+
+			index[key] = len(hostPorts)
+			hostPorts = append(hostPorts, hostPort{
+				host:    rule.Host,
+				port:    port,
+				path:    prefix,
+				service: path.Backend.Service.Name,
+				ingress: ingress,
+			})
 		}
 	}
 
 	return hostPorts, nil
 }
 
-func getEurekaApplication(
+func (h *Handler) getEurekaApplication(
 	ctx context.Context,
 	c client.Client,
 	spec *discoveryv1.EurekaApplication,
-	environment string,
+	t target,
 	resourceName string,
-	logger logr.Logger,
 ) (*eurek8ssyncer.Application, error) {
-	zone := spec.Spec.Zone
-	if zone == "" {
-		zone = DefaultZone
+	environment, zone := t.environment, t.zone
+	if _, ok := h.environments[environment]; !ok {
+		return nil, newError(CategoryUnknownEnvironment, fmt.Errorf("environment %s is not configured", environment))
 	}
 
-	metadata := map[string]string{"zone": zone}
-	if zone == NoZone {
-		metadata = map[string]string{}
+	ingresses, err := Ingresses(ctx, c, spec)
+	if err != nil {
+		h.log.Error(err, "Error retrieving Ingress...")
+		return nil, err
 	}
 
-	var ingress networkingv1.Ingress
-	nn := types.NamespacedName{Namespace: spec.Namespace, Name: spec.Spec.IngressName}
-	if err := c.Get(ctx, nn, &ingress); err != nil {
-		logger.Error(err, "Error retrieving Ingress...")
+	app := &eurek8ssyncer.Application{
+		ResourceName:      resourceName,
+		CreationTimestamp: spec.CreationTimestamp.Time,
+		Environment:       environment,
+		Name:              spec.Spec.AppName,
+		Status:            fargo.StatusType(statusOverride(spec, time.Now())),
+		AsgStatus:         string(spec.Spec.AsgStatus),
+		Sources:           make(map[string]string),
+	}
+
+	hostPorts, err := getHostPorts(ctx, c, ingresses)
+	if err != nil {
 		return nil, err
 	}
 
-	app := &eurek8ssyncer.Application{
-		ResourceName: resourceName,
-		Environment:  environment,
-		Name:         spec.Spec.AppName,
+	if app.Status == fargo.UP && endpointsDown(spec) {
+		app.Status = fargo.DOWN
 	}
 
-	hostPorts, err := getHostPorts(ctx, c, ingress)
+	// instances stay STARTING until the load balancers are provisioned, status overrides taking precedence
+	waiting := make(map[string]bool)
+	if spec.Spec.WaitForLoadBalancer {
+		for _, ingress := range ingresses {
+			if !hasLoadBalancer(ingress) {
+				waiting[ingress.Name] = true
+			}
+		}
+
+		if len(waiting) > 0 && app.Status == fargo.UP {
+			h.log.Info("waiting for the load balancer of the ingresses", "environment", environment)
+			app.Status = fargo.STARTING
+		}
+	}
+
+	templates, err := parseInstanceTemplates(spec)
 	if err != nil {
 		return nil, err
 	}
 
+	dataCenter := mergeDataCenter(h.environments[environment].DataCenter, spec.Spec.DataCenterInfo)
+	app.AsgNames = make(map[string]string)
+
 	for _, hostPort := range hostPorts {
 		rawHost, rawPort := hostPort.host, hostPort.port
 		protocol := protocolHttp
 		if rawPort == httpsPort {
 			protocol = protocolHttps
 		}
-		host := fmt.Sprintf("%s://%s:%d", protocol, rawHost, rawPort)
+		host := fmt.Sprintf("%s://%s:%d%s", protocol, rawHost, rawPort, hostPort.path)
+
+		paths, err := probePaths(ctx, c, spec.Spec.PathsFromProbes, spec.Namespace, hostPort.service, t.paths)
+		if err != nil {
+			return nil, err
+		}
+
+		statusUrl, err := util.JoinPathStr(host, paths.Status)
+		if err != nil {
+			return nil, newError(CategoryInvalidSpec, errors.Wrap(err, "invalid host or path set for application status address"))
+		}
 
-		statusUrl, err := util.JoinPathStr(host, spec.Spec.Paths.Status)
+		healthCheckUrl, err := util.JoinPathStr(host, paths.HealthCheck)
 		if err != nil {
-			return nil, errors.Wrap(err, "invalid host or path set for application status address")
+			return nil, newError(CategoryInvalidSpec, errors.Wrap(err, "invalid host or path set for application healthcheck address"))
 		}
 
-		healthCheckUrl, err := util.JoinPathStr(host, spec.Spec.Paths.HealthCheck)
+		homeUrl, err := util.JoinPathStr(host, paths.Home)
 		if err != nil {
-			return nil, errors.Wrap(err, "invalid host or path set for application healthcheck address")
+			return nil, newError(CategoryInvalidSpec, errors.Wrap(err, "invalid host or path set for application home address"))
+		}
+
+		addressSource := spec.Spec.AddressSource
+		if addressSource == discoveryv1.AddressSourceLoadBalancer && waiting[hostPort.ingress.Name] {
+			addressSource = discoveryv1.AddressSourceHostname
 		}
 
-		homeUrl, err := util.JoinPathStr(host, spec.Spec.Paths.Home)
+		ipAddr, err := getIPAddress(ctx, addressSource, rawHost, *hostPort.ingress)
 		if err != nil {
-			return nil, errors.Wrap(err, "invalid host or path set for application home address")
+			return nil, err
+		}
+
+		var workload string
+		if templates.workload {
+			if workload, err = workloadName(ctx, c, spec.Namespace, hostPort.service); err != nil {
+				return nil, err
+			}
+		}
+
+		fields, err := templates.execute(instanceData{
+			AppName:     app.Name,
+			Host:        rawHost,
+			Port:        rawPort,
+			Path:        hostPort.path,
+			Namespace:   spec.Namespace,
+			Name:        spec.Name,
+			Environment: environment,
+			Ingress:     hostPort.ingress.Name,
+			Workload:    workload,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if source, ok := app.Sources[fields.instanceID]; ok {
+			return nil, invalidSpec("instance id %s is produced for several hosts, by %s and %s", fields.instanceID, source, hostPort.ingress.Name)
 		}
 
 		i := &fargo.Instance{
 			UniqueID: func(i fargo.Instance) string {
-				return strings.ToLower(fmt.Sprintf("%s:%s:%d", i.App, i.HostName, i.Port))
+				return strings.ToLower(i.Id())
 			},
-			InstanceId:       strings.ToLower(fmt.Sprintf("%s:%s:%d", app.Name, rawHost, rawPort)),
+			InstanceId:       fields.instanceID,
 			HostName:         rawHost,
-			IPAddr:           rawHost,
+			IPAddr:           ipAddr,
 			App:              app.Name,
-			VipAddress:       app.Name,
-			SecureVipAddress: app.Name,
+			VipAddress:       fields.vipAddress,
+			SecureVipAddress: fields.secureVipAddress,
 			HomePageUrl:      homeUrl,
 			StatusPageUrl:    statusUrl,
 			HealthCheckUrl:   healthCheckUrl,
 			Status:           fargo.UP,
 			Port:             int(rawPort),
 			PortEnabled:      true,
-			DataCenterInfo:   fargo.DataCenterInfo{Name: fargo.MyOwn},
 			Metadata:         fargo.InstanceMetadata{},
 		}
 
-		for key, value := range metadata {
+		if i.DataCenterInfo, err = getDataCenterInfo(ctx, c, dataCenter, i, spec.Namespace, hostPort.service); err != nil {
+			return nil, err
+		}
+
+		for key, value := range t.metadata {
 			i.SetMetadataString(key, value)
 		}
 
+		instanceZone := zone
+		if zone == AutoZone {
+			if instanceZone, err = detectZone(ctx, c, spec.Spec.ZoneDetection, spec.Namespace, hostPort.service); err != nil {
+				return nil, err
+			} else if instanceZone == "" {
+				h.log.Info("unable to detect zone, using default", "host", rawHost, "zone", DefaultZone)
+				instanceZone = DefaultZone
+			}
+		}
+
+		if instanceZone != NoZone {
+			i.SetMetadataString("zone", instanceZone)
+		}
+
 		if rawPort == httpsPort {
 			i.SecurePort = i.Port
 			i.SecurePortEnabled = true
@@ -215,6 +660,14 @@ func getEurekaApplication(
 		}
 
 		app.Instances = append(app.Instances, i)
+		app.Sources[i.InstanceId] = hostPort.ingress.Name
+		if fields.asgName != "" {
+			app.AsgNames[i.InstanceId] = fields.asgName
+		}
+	}
+
+	if len(app.Instances) == 0 {
+		return nil, invalidSpec("ingresses have no rules to register")
 	}
 
 	return app, nil
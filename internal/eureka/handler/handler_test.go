@@ -0,0 +1,111 @@
+package handler
+
+import (
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	eurek8ssyncer "github.com/eurek8s/controller/internal/eureka/sync"
+	"github.com/hudl/fargo"
+	"testing"
+)
+
+func TestSetStatusWorstAcrossEnvironments(t *testing.T) {
+	tests := []struct {
+		name   string
+		status []fargo.StatusType
+		want   discoveryv1.InstanceStatus
+	}{
+		{
+			name: "no environment",
+			want: "",
+		},
+		{
+			name:   "single environment",
+			status: []fargo.StatusType{fargo.OUTOFSERVICE},
+			want:   discoveryv1.InstanceStatusOutOfService,
+		},
+		{
+			name:   "down before up",
+			status: []fargo.StatusType{fargo.DOWN, fargo.UP},
+			want:   discoveryv1.InstanceStatusDown,
+		},
+		{
+			name:   "up before down",
+			status: []fargo.StatusType{fargo.UP, fargo.DOWN},
+			want:   discoveryv1.InstanceStatusDown,
+		},
+		{
+			name:   "out of service and starting",
+			status: []fargo.StatusType{fargo.STARTING, fargo.OUTOFSERVICE, fargo.UP},
+			want:   discoveryv1.InstanceStatusOutOfService,
+		},
+		{
+			name:   "unknown status",
+			status: []fargo.StatusType{fargo.DOWN, fargo.UNKNOWN},
+			want:   discoveryv1.InstanceStatus(fargo.UNKNOWN),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apps []*eurek8ssyncer.Application
+			for _, s := range tt.status {
+				apps = append(apps, &eurek8ssyncer.Application{Environment: string(s), Status: s})
+			}
+
+			spec := &discoveryv1.EurekaApplication{}
+			setStatus(spec, apps)
+
+			if spec.Status.Status != tt.want {
+				t.Errorf("status = %q, want %q", spec.Status.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppliedAsgStatus(t *testing.T) {
+	asgNames := map[string]string{"orders-1": "orders-blue"}
+
+	tests := []struct {
+		name string
+		apps []*eurek8ssyncer.Application
+		want discoveryv1.AsgStatus
+	}{
+		{
+			name: "no environment",
+			want: "",
+		},
+		{
+			name: "applied",
+			apps: []*eurek8ssyncer.Application{{AsgStatus: "DISABLED", AsgNames: asgNames}},
+			want: discoveryv1.AsgStatusDisabled,
+		},
+		{
+			name: "without asg",
+			apps: []*eurek8ssyncer.Application{{AsgStatus: "DISABLED"}},
+			want: "",
+		},
+		{
+			name: "applied in every environment",
+			apps: []*eurek8ssyncer.Application{
+				{AsgStatus: "ENABLED", AsgNames: asgNames},
+				{AsgStatus: "ENABLED", AsgNames: asgNames},
+			},
+			want: discoveryv1.AsgStatusEnabled,
+		},
+		{
+			name: "failed in one environment",
+			apps: []*eurek8ssyncer.Application{
+				{AsgStatus: "DISABLED", AsgNames: asgNames},
+				{AsgNames: asgNames},
+			},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appliedAsgStatus(tt.apps); got != tt.want {
+				t.Errorf("appliedAsgStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+)
+
+// Ingresses returns the ingresses referenced by the application, by name or selector, without duplicates
+func Ingresses(ctx context.Context, c client.Reader, spec *discoveryv1.EurekaApplication) ([]networkingv1.Ingress, error) {
+	var names []string
+	if spec.Spec.IngressName != "" {
+		names = append(names, spec.Spec.IngressName)
+	}
+	names = append(names, spec.Spec.Ingresses...)
+
+	seen := make(map[string]bool)
+	var ingresses []networkingv1.Ingress
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var ingress networkingv1.Ingress
+		if err := c.Get(ctx, types.NamespacedName{Namespace: spec.Namespace, Name: name}, &ingress); err != nil {
+			return nil, err
+		}
+
+		ingresses = append(ingresses, ingress)
+	}
+
+	if spec.Spec.IngressSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.Spec.IngressSelector)
+		if err != nil {
+			return nil, invalidSpec("invalid ingressSelector: %s", err)
+		}
+
+		var list networkingv1.IngressList
+		if err := c.List(ctx, &list, client.InNamespace(spec.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+
+		sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+		for _, ingress := range list.Items {
+			if !seen[ingress.Name] {
+				seen[ingress.Name] = true
+				ingresses = append(ingresses, ingress)
+			}
+		}
+
+		if len(ingresses) == 0 {
+			return nil, apierrors.NewNotFound(networkingv1.Resource("ingresses"), selector.String())
+		}
+	}
+
+	return ingresses, nil
+}
+
+// ReferencesIngress reports whether the application references the ingress, by name or selector
+func ReferencesIngress(spec *discoveryv1.EurekaApplication, ingress *networkingv1.Ingress) bool {
+	if spec.Namespace != ingress.Namespace {
+		return false
+	}
+
+	if spec.Spec.IngressName == ingress.Name {
+		return true
+	}
+
+	for _, name := range spec.Spec.Ingresses {
+		if name == ingress.Name {
+			return true
+		}
+	}
+
+	if spec.Spec.IngressSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.Spec.IngressSelector)
+		return err == nil && selector.Matches(labels.Set(ingress.Labels))
+	}
+
+	return false
+}
+
+// hasIngressReference reports whether the application references any ingress
+func hasIngressReference(spec *discoveryv1.EurekaApplication) bool {
+	return spec.Spec.IngressName != "" || len(spec.Spec.Ingresses) > 0 || spec.Spec.IngressSelector != nil
+}
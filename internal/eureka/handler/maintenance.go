@@ -0,0 +1,49 @@
+package handler
+
+import (
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"time"
+)
+
+// statusOverride returns the status that should be applied to the instances at the given time
+func statusOverride(spec *discoveryv1.EurekaApplication, now time.Time) discoveryv1.InstanceStatus {
+	if w := spec.Spec.MaintenanceWindow; w != nil && !now.Before(w.Start.Time) && now.Before(w.End.Time) {
+		if w.Status == "" {
+			return discoveryv1.InstanceStatusOutOfService
+		}
+
+		return w.Status
+	}
+
+	if spec.Spec.Status == "" {
+		return discoveryv1.InstanceStatusUp
+	}
+
+	return spec.Spec.Status
+}
+
+// RequeueAfter returns the time left until the next maintenance window boundary, the expiry of the registration or
+// the end of the endpoints hysteresis, or zero if there is none
+func RequeueAfter(spec *discoveryv1.EurekaApplication, now time.Time) time.Duration {
+	var next time.Duration
+	after := func(t time.Time) {
+		if d := t.Sub(now); d > 0 && (next == 0 || d < next) {
+			next = d
+		}
+	}
+
+	if w := spec.Spec.MaintenanceWindow; w != nil {
+		after(w.Start.Time)
+		after(w.End.Time)
+	}
+
+	if t := expiry(spec); t != nil {
+		after(*t)
+	}
+
+	if t := endpointsRecovery(spec); t != nil {
+		after(*t)
+	}
+
+	return next
+}
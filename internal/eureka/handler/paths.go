@@ -0,0 +1,42 @@
+package handler
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	"path"
+	"strings"
+)
+
+// regexChars are the characters of ImplementationSpecific paths used as regular expressions, i.e by ingress-nginx
+const regexChars = "()[]{}*+?|^$\\"
+
+// pathPrefix returns the prefix the application is exposed at by the ingress path, empty for the host root.
+// Exact paths expose a single page, the application is taken to be exposed at their parent.
+func pathPrefix(p networkingv1.HTTPIngressPath) string {
+	if p.Path == "" || (p.PathType != nil && *p.PathType == networkingv1.PathTypeImplementationSpecific &&
+		strings.ContainsAny(p.Path, regexChars)) {
+		return ""
+	}
+
+	prefix := path.Clean("/" + p.Path)
+	if p.PathType != nil && *p.PathType == networkingv1.PathTypeExact {
+		prefix = path.Dir(prefix)
+	}
+
+	if prefix == "/" {
+		return ""
+	}
+
+	return prefix
+}
+
+// commonPathPrefix returns the longest common prefix of two path prefixes, by path segment
+func commonPathPrefix(a, b string) string {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+
+	var common []string
+	for i := 0; i < len(as) && i < len(bs) && as[i] == bs[i]; i++ {
+		common = append(common, as[i])
+	}
+
+	return strings.Join(common, "/")
+}
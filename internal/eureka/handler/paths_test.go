@@ -0,0 +1,121 @@
+package handler
+
+import (
+	networkingv1 "k8s.io/api/networking/v1"
+	"testing"
+)
+
+func TestPathPrefix(t *testing.T) {
+	prefix := networkingv1.PathTypePrefix
+	exact := networkingv1.PathTypeExact
+	implementationSpecific := networkingv1.PathTypeImplementationSpecific
+
+	tests := []struct {
+		name string
+		path networkingv1.HTTPIngressPath
+		want string
+	}{
+		{
+			name: "empty",
+			path: networkingv1.HTTPIngressPath{PathType: &prefix},
+			want: "",
+		},
+		{
+			name: "root",
+			path: networkingv1.HTTPIngressPath{Path: "/", PathType: &prefix},
+			want: "",
+		},
+		{
+			name: "prefix",
+			path: networkingv1.HTTPIngressPath{Path: "/orders", PathType: &prefix},
+			want: "/orders",
+		},
+		{
+			name: "trailing slash",
+			path: networkingv1.HTTPIngressPath{Path: "/orders/api/", PathType: &prefix},
+			want: "/orders/api",
+		},
+		{
+			name: "exact",
+			path: networkingv1.HTTPIngressPath{Path: "/orders/health", PathType: &exact},
+			want: "/orders",
+		},
+		{
+			name: "exact at the root",
+			path: networkingv1.HTTPIngressPath{Path: "/health", PathType: &exact},
+			want: "",
+		},
+		{
+			name: "implementation specific",
+			path: networkingv1.HTTPIngressPath{Path: "/orders", PathType: &implementationSpecific},
+			want: "/orders",
+		},
+		{
+			name: "regular expression",
+			path: networkingv1.HTTPIngressPath{Path: "/orders(/|$)(.*)", PathType: &implementationSpecific},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pathPrefix(tt.path); got != tt.want {
+				t.Errorf("pathPrefix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonPathPrefix(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "same",
+			a:    "/orders/api",
+			b:    "/orders/api",
+			want: "/orders/api",
+		},
+		{
+			name: "shared segment",
+			a:    "/orders/api",
+			b:    "/orders/admin",
+			want: "/orders",
+		},
+		{
+			name: "nested",
+			a:    "/orders",
+			b:    "/orders/api",
+			want: "/orders",
+		},
+		{
+			name: "no shared segment",
+			a:    "/orders",
+			b:    "/payments",
+			want: "",
+		},
+		{
+			name: "shared characters only",
+			a:    "/orders-api",
+			b:    "/orders-admin",
+			want: "",
+		},
+		{
+			name: "host root",
+			a:    "",
+			b:    "/orders",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := commonPathPrefix(tt.a, tt.b); got != tt.want {
+				t.Errorf("commonPathPrefix(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"github.com/eurek8s/controller/internal/eureka/util"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"path"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// CheckPolicy returns a CategoryPolicyDenied error when no EurekaEnvironmentPolicy of the environment allows the
+// namespace and app name, environments without policy being open to every namespace
+func CheckPolicy(ctx context.Context, c client.Reader, namespace, appName, environment string) error {
+	var policies discoveryv1.EurekaEnvironmentPolicyList
+	if err := c.List(ctx, &policies); err != nil {
+		return err
+	}
+
+	var ns *v1.Namespace
+	restricted := false
+	for _, p := range policies.Items {
+		if !util.ContainsString(p.Spec.Environments, environment) {
+			continue
+		}
+		restricted = true
+
+		if ns == nil {
+			ns = &v1.Namespace{}
+			if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+				return err
+			}
+		}
+
+		allowed, err := policyAllows(&p, ns, appName)
+		if err != nil {
+			return newError(CategoryInvalidSpec, errors.Wrapf(err, "invalid policy %s", p.Name))
+		}
+
+		if allowed {
+			return nil
+		}
+	}
+
+	if restricted {
+		return newError(CategoryPolicyDenied, errors.Errorf(
+			"namespace %s is not allowed to register app %s into environment %s", namespace, appName, environment))
+	}
+
+	return nil
+}
+
+func policyAllows(p *discoveryv1.EurekaEnvironmentPolicy, ns *v1.Namespace, appName string) (bool, error) {
+	allowed := util.ContainsString(p.Spec.Namespaces, ns.Name)
+	if !allowed && p.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(p.Spec.NamespaceSelector)
+		if err != nil {
+			return false, err
+		}
+
+		allowed = selector.Matches(labels.Set(ns.Labels))
+	}
+
+	if !allowed || len(p.Spec.AppNames) == 0 {
+		return allowed, nil
+	}
+
+	// Eureka upper-cases the app names, they are matched regardless of the case
+	for _, pattern := range p.Spec.AppNames {
+		matched, err := path.Match(strings.ToUpper(pattern), strings.ToUpper(appName))
+		if err != nil {
+			return false, err
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestPolicyAllows(t *testing.T) {
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}}
+
+	tests := []struct {
+		name    string
+		spec    discoveryv1.EurekaEnvironmentPolicySpec
+		appName string
+		want    bool
+	}{
+		{
+			name:    "namespace listed",
+			spec:    discoveryv1.EurekaEnvironmentPolicySpec{Namespaces: []string{"orders", "payments"}},
+			appName: "payments-api",
+			want:    true,
+		},
+		{
+			name:    "namespace not listed",
+			spec:    discoveryv1.EurekaEnvironmentPolicySpec{Namespaces: []string{"orders"}},
+			appName: "payments-api",
+			want:    false,
+		},
+		{
+			name: "namespace selected",
+			spec: discoveryv1.EurekaEnvironmentPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+			},
+			appName: "payments-api",
+			want:    true,
+		},
+		{
+			name:    "app name matching",
+			spec:    discoveryv1.EurekaEnvironmentPolicySpec{Namespaces: []string{"payments"}, AppNames: []string{"payments-*"}},
+			appName: "payments-api",
+			want:    true,
+		},
+		{
+			name:    "app name not matching",
+			spec:    discoveryv1.EurekaEnvironmentPolicySpec{Namespaces: []string{"payments"}, AppNames: []string{"payments-*"}},
+			appName: "orders-api",
+			want:    false,
+		},
+		{
+			name:    "upper-cased app name",
+			spec:    discoveryv1.EurekaEnvironmentPolicySpec{Namespaces: []string{"payments"}, AppNames: []string{"payments-*"}},
+			appName: "PAYMENTS-API",
+			want:    true,
+		},
+		{
+			name:    "upper-cased pattern",
+			spec:    discoveryv1.EurekaEnvironmentPolicySpec{Namespaces: []string{"payments"}, AppNames: []string{"PAYMENTS-*"}},
+			appName: "payments-api",
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policyAllows(&discoveryv1.EurekaEnvironmentPolicy{Spec: tt.spec}, ns, tt.appName)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("policyAllows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// workloadStatusPathAnnotation sets the status path on the Deployment or StatefulSet, same as on ingresses
+const workloadStatusPathAnnotation = "eurek8s.com/status-path"
+
+// probePaths fills the healthcheck and status paths left empty with the probes of the workload behind the service
+func probePaths(
+	ctx context.Context,
+	c client.Client,
+	opts *discoveryv1.PathsFromProbes,
+	namespace, serviceName string,
+	paths discoveryv1.EurekaApplicationPaths,
+) (discoveryv1.EurekaApplicationPaths, error) {
+	if opts == nil || (paths.HealthCheck != "" && paths.Status != "") {
+		return paths, nil
+	}
+
+	workload, template, err := getWorkload(ctx, c, namespace, serviceName)
+	if err != nil || template == nil {
+		return paths, err
+	}
+
+	container := probeContainer(template.Spec.Containers, opts.Container)
+	if container == nil {
+		return paths, nil
+	}
+
+	if paths.HealthCheck == "" {
+		paths.HealthCheck = probePath(container.ReadinessProbe)
+	}
+
+	if paths.Status == "" {
+		if p := workload.GetAnnotations()[workloadStatusPathAnnotation]; p != "" {
+			paths.Status = p
+		} else {
+			switch opts.StatusProbe {
+			case discoveryv1.ProbeSourceReadiness:
+				paths.Status = probePath(container.ReadinessProbe)
+			case discoveryv1.ProbeSourceStartup:
+				paths.Status = probePath(container.StartupProbe)
+			default:
+				paths.Status = probePath(container.LivenessProbe)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// getWorkload returns the Deployment or StatefulSet owning the pods selected by the service, nil if there is none
+func getWorkload(
+	ctx context.Context,
+	c client.Client,
+	namespace, serviceName string,
+) (client.Object, *v1.PodTemplateSpec, error) {
+	var service v1.Service
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: serviceName}, &service); err != nil {
+		return nil, nil, err
+	}
+
+	if len(service.Spec.Selector) == 0 {
+		return nil, nil, nil
+	}
+
+	var pods v1.PodList
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	if err := c.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, nil, err
+	}
+
+	for _, pod := range pods.Items {
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil {
+			continue
+		}
+
+		switch owner.Kind {
+		case "StatefulSet":
+			var sts appsv1.StatefulSet
+			if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, &sts); err != nil {
+				return nil, nil, client.IgnoreNotFound(err)
+			}
+
+			return &sts, &sts.Spec.Template, nil
+		case "ReplicaSet":
+			var rs appsv1.ReplicaSet
+			if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, &rs); err != nil {
+				return nil, nil, client.IgnoreNotFound(err)
+			}
+
+			if owner = metav1.GetControllerOf(&rs); owner == nil || owner.Kind != "Deployment" {
+				continue
+			}
+
+			var deployment appsv1.Deployment
+			if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, &deployment); err != nil {
+				return nil, nil, client.IgnoreNotFound(err)
+			}
+
+			return &deployment, &deployment.Spec.Template, nil
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// workloadName returns the name of the Deployment or StatefulSet behind the service, empty if there is none
+func workloadName(ctx context.Context, c client.Client, namespace, serviceName string) (string, error) {
+	workload, _, err := getWorkload(ctx, c, namespace, serviceName)
+	if err != nil || workload == nil {
+		return "", client.IgnoreNotFound(err)
+	}
+
+	return workload.GetName(), nil
+}
+
+func probeContainer(containers []v1.Container, name string) *v1.Container {
+	for i := range containers {
+		if name != "" && containers[i].Name == name {
+			return &containers[i]
+		} else if name == "" && probePath(containers[i].ReadinessProbe) != "" {
+			return &containers[i]
+		}
+	}
+
+	return nil
+}
+
+// probePath returns the path of an HTTP probe, without its query
+func probePath(probe *v1.Probe) string {
+	if probe == nil || probe.HTTPGet == nil {
+		return ""
+	}
+
+	p := probe.HTTPGet.Path
+	if i := strings.Index(p, "?"); i >= 0 {
+		p = p[:i]
+	}
+
+	return p
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bytes"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"strings"
+	"text/template"
+)
+
+const defaultInstanceIDTemplate = "{{.AppName}}:{{.Host}}:{{.Port}}"
+
+// appNameData is the data the app name template is executed with
+type appNameData struct {
+	Name            string
+	Namespace       string
+	Labels          map[string]string
+	NamespaceLabels map[string]string
+}
+
+// instanceData is the data the instance templates are executed with
+type instanceData struct {
+	AppName     string
+	Host        string
+	Port        int32
+	Path        string
+	Namespace   string
+	Name        string
+	Environment string
+	Ingress     string
+	// Workload is the name of the Deployment or StatefulSet behind the ingress backend
+	Workload string
+}
+
+// instanceTemplates are the parsed templates of the fields of the instances
+type instanceTemplates struct {
+	instanceID       *template.Template
+	vipAddress       *template.Template
+	secureVipAddress *template.Template
+	asgName          *template.Template
+
+	// the default instance id is lower cased, custom ones are kept as is to match existing registrations
+	lowerInstanceID bool
+	// the workload is only looked up for the templates using it
+	workload bool
+}
+
+// instanceFields are the fields of an instance resulting from the templates
+type instanceFields struct {
+	instanceID       string
+	vipAddress       string
+	secureVipAddress string
+	asgName          string
+}
+
+func parseInstanceTemplates(spec *discoveryv1.EurekaApplication) (*instanceTemplates, error) {
+	t := &instanceTemplates{}
+
+	instanceID := spec.Spec.InstanceID
+	if instanceID == "" {
+		instanceID = defaultInstanceIDTemplate
+		t.lowerInstanceID = true
+	}
+
+	for _, f := range []struct {
+		name string
+		text string
+		dst  **template.Template
+	}{
+		{"instanceId", instanceID, &t.instanceID},
+		{"vipAddress", spec.Spec.VipAddress, &t.vipAddress},
+		{"secureVipAddress", spec.Spec.SecureVipAddress, &t.secureVipAddress},
+		{"asgName", spec.Spec.AsgName, &t.asgName},
+	} {
+		if f.text == "" {
+			continue
+		}
+
+		tpl, err := template.New(f.name).Option("missingkey=error").Parse(f.text)
+		if err != nil {
+			return nil, invalidSpec("invalid %s template: %s", f.name, err)
+		}
+
+		*f.dst = tpl
+		t.workload = t.workload || strings.Contains(f.text, ".Workload")
+	}
+
+	return t, nil
+}
+
+func (t *instanceTemplates) execute(data instanceData) (instanceFields, error) {
+	fields := instanceFields{vipAddress: data.AppName, secureVipAddress: data.AppName}
+
+	for _, f := range []struct {
+		tpl *template.Template
+		dst *string
+	}{
+		{t.instanceID, &fields.instanceID},
+		{t.vipAddress, &fields.vipAddress},
+		{t.secureVipAddress, &fields.secureVipAddress},
+		{t.asgName, &fields.asgName},
+	} {
+		if f.tpl == nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := f.tpl.Execute(&buf, data); err != nil {
+			return fields, invalidSpec("unable to execute %s template: %s", f.tpl.Name(), err)
+		}
+
+		*f.dst = buf.String()
+	}
+
+	if t.lowerInstanceID {
+		fields.instanceID = strings.ToLower(fields.instanceID)
+	}
+
+	if fields.instanceID == "" {
+		return fields, invalidSpec("instanceId template produced an empty id for host %s", data.Host)
+	}
+
+	return fields, nil
+}
+
+// ValidateInstanceTemplates parses the templates of the application and executes them with sample data
+func ValidateInstanceTemplates(spec *discoveryv1.EurekaApplication, fldPath *field.Path) field.ErrorList {
+	data := instanceData{
+		AppName:     spec.Spec.AppName,
+		Host:        "example.com",
+		Port:        httpsPort,
+		Namespace:   spec.Namespace,
+		Name:        spec.Name,
+		Environment: Environments(spec)[0],
+		Ingress:     spec.Spec.IngressName,
+		Workload:    spec.Spec.AppName,
+	}
+
+	var errs field.ErrorList
+	for _, f := range []struct {
+		name string
+		text string
+	}{
+		{"instanceId", spec.Spec.InstanceID},
+		{"vipAddress", spec.Spec.VipAddress},
+		{"secureVipAddress", spec.Spec.SecureVipAddress},
+		{"asgName", spec.Spec.AsgName},
+	} {
+		if f.text == "" {
+			continue
+		}
+
+		tpl, err := template.New(f.name).Option("missingkey=error").Parse(f.text)
+		if err == nil {
+			err = tpl.Execute(io.Discard, data)
+		}
+
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child(f.name), f.text, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func isTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// executeAppName executes the app name template of the application, i.e to tell preview instances from the main app
+func executeAppName(text string, app *discoveryv1.EurekaApplication, ns *v1.Namespace) (string, error) {
+	tpl, err := template.New("appName").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", invalidSpec("invalid appName template: %s", err)
+	}
+
+	var buf bytes.Buffer
+	data := appNameData{Name: app.Name, Namespace: app.Namespace, Labels: app.Labels, NamespaceLabels: ns.Labels}
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", invalidSpec("unable to execute appName template: %s", err)
+	}
+
+	if buf.Len() == 0 {
+		return "", invalidSpec("appName template produced an empty name")
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"testing"
+)
+
+func TestInstanceTemplates(t *testing.T) {
+	data := instanceData{
+		AppName:     "ORDERS",
+		Host:        "Orders.Example.com",
+		Port:        443,
+		Path:        "/orders",
+		Namespace:   "shop",
+		Name:        "orders",
+		Environment: "qa",
+		Ingress:     "orders-ingress",
+		Workload:    "orders-api",
+	}
+
+	tests := []struct {
+		name         string
+		spec         discoveryv1.EurekaApplicationSpec
+		want         instanceFields
+		wantWorkload bool
+		wantErr      bool
+	}{
+		{
+			name: "defaults",
+			want: instanceFields{instanceID: "orders:orders.example.com:443", vipAddress: "ORDERS", secureVipAddress: "ORDERS"},
+		},
+		{
+			name: "custom instance id kept as is",
+			spec: discoveryv1.EurekaApplicationSpec{InstanceID: "{{.Host}}-{{.Environment}}"},
+			want: instanceFields{instanceID: "Orders.Example.com-qa", vipAddress: "ORDERS", secureVipAddress: "ORDERS"},
+		},
+		{
+			name: "vip addresses and asg name",
+			spec: discoveryv1.EurekaApplicationSpec{
+				VipAddress:       "{{.AppName}}.{{.Namespace}}",
+				SecureVipAddress: "{{.AppName}}.secure",
+				AsgName:          "{{.Name}}-{{.Ingress}}",
+			},
+			want: instanceFields{
+				instanceID:       "orders:orders.example.com:443",
+				vipAddress:       "ORDERS.shop",
+				secureVipAddress: "ORDERS.secure",
+				asgName:          "orders-orders-ingress",
+			},
+		},
+		{
+			name:         "workload",
+			spec:         discoveryv1.EurekaApplicationSpec{AsgName: "{{.Workload}}"},
+			want:         instanceFields{instanceID: "orders:orders.example.com:443", vipAddress: "ORDERS", secureVipAddress: "ORDERS", asgName: "orders-api"},
+			wantWorkload: true,
+		},
+		{
+			name:    "empty instance id",
+			spec:    discoveryv1.EurekaApplicationSpec{InstanceID: "{{if false}}x{{end}}"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			spec:    discoveryv1.EurekaApplicationSpec{VipAddress: "{{.Unknown}}"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := parseInstanceTemplates(&discoveryv1.EurekaApplication{Spec: tt.spec})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if templates.workload != tt.wantWorkload {
+				t.Errorf("workload = %v, want %v", templates.workload, tt.wantWorkload)
+			}
+
+			got, err := templates.execute(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("execute() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("execute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInstanceTemplatesInvalid(t *testing.T) {
+	_, err := parseInstanceTemplates(&discoveryv1.EurekaApplication{
+		Spec: discoveryv1.EurekaApplicationSpec{InstanceID: "{{.Host"},
+	})
+
+	if Classify(err) != CategoryInvalidSpec {
+		t.Errorf("parseInstanceTemplates() error = %v, want an invalid spec", err)
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	zoneLabel         = "topology.kubernetes.io/zone"
+	instanceTypeLabel = "node.kubernetes.io/instance-type"
+)
+
+// getBackendNodes returns the nodes running the ready endpoints of the service, once per endpoint
+func getBackendNodes(ctx context.Context, c client.Client, namespace, serviceName string) ([]v1.Node, error) {
+	var endpoints v1.Endpoints
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: serviceName}, &endpoints); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	var nodeNames []string
+	for _, subset := range endpoints.Subsets {
+		for _, address := range subset.Addresses {
+			if address.NodeName != nil {
+				nodeNames = append(nodeNames, *address.NodeName)
+			}
+		}
+	}
+
+	return getNodes(ctx, c, nodeNames)
+}
+
+// getIngressControllerNodes returns the nodes running the ready ingress controller pods, once per pod
+func getIngressControllerNodes(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	selector *metav1.LabelSelector,
+) ([]v1.Node, error) {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var pods v1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: s}); err != nil {
+		return nil, err
+	}
+
+	var nodeNames []string
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" && isPodReady(pod) {
+			nodeNames = append(nodeNames, pod.Spec.NodeName)
+		}
+	}
+
+	return getNodes(ctx, c, nodeNames)
+}
+
+func getNodes(ctx context.Context, c client.Client, nodeNames []string) ([]v1.Node, error) {
+	var nodes []v1.Node
+	cache := make(map[string]*v1.Node)
+	for _, name := range nodeNames {
+		node, ok := cache[name]
+		if !ok {
+			node = &v1.Node{}
+			if err := c.Get(ctx, types.NamespacedName{Name: name}, node); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return nil, err
+				}
+				node = nil
+			}
+			cache[name] = node
+		}
+
+		if node != nil {
+			nodes = append(nodes, *node)
+		}
+	}
+
+	return nodes, nil
+}
+
+func isPodReady(pod v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// majorityZone returns the zone where most of the nodes are, ties are broken by name
+func majorityZone(nodes []v1.Node) string {
+	counts := make(map[string]int)
+	var zone string
+	for _, node := range nodes {
+		z := node.Labels[zoneLabel]
+		if z == "" {
+			continue
+		}
+
+		counts[z]++
+		if counts[z] > counts[zone] || (counts[z] == counts[zone] && z < zone) {
+			zone = z
+		}
+	}
+
+	return zone
+}
+
+// detectZone returns the zone of the instance backed by the service, or an empty string if it cannot be found
+func detectZone(
+	ctx context.Context,
+	c client.Client,
+	detection *discoveryv1.ZoneDetection,
+	namespace string,
+	serviceName string,
+) (string, error) {
+	var nodes []v1.Node
+	var err error
+
+	if detection != nil && detection.Source == discoveryv1.ZoneSourceIngressController {
+		nodes, err = getIngressControllerNodes(ctx, c, detection.IngressControllerNamespace, detection.IngressControllerSelector)
+	} else {
+		nodes, err = getBackendNodes(ctx, c, namespace, serviceName)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return majorityZone(nodes), nil
+}
@@ -2,11 +2,26 @@ package sync
 
 import (
 	"github.com/hudl/fargo"
+	"time"
 )
 
 type Application struct {
-	ResourceName string
-	Environment  string
-	Name         string
-	Instances    []*fargo.Instance
+	ResourceName      string
+	CreationTimestamp time.Time
+	Environment       string
+	Name              string
+	Status            fargo.StatusType
+	Instances         []*fargo.Instance
+	Conflicts         []Conflict
+	// Sources are the names of the objects the instances come from, by instance id
+	Sources map[string]string
+	// AsgNames are the names of the ASG of the instances, by instance id
+	AsgNames map[string]string
+	// AsgStatus is the status set on the ASGs of the instances, they are left as they are when empty
+	AsgStatus string
+}
+
+// key identifies the application of a resource in an environment
+func (a *Application) key() string {
+	return a.ResourceName + "@" + a.Environment
 }
@@ -0,0 +1,104 @@
+package sync
+
+import (
+	"github.com/hudl/fargo"
+	"strings"
+)
+
+// Conflict is an instance claimed by an application but owned by another one
+type Conflict struct {
+	InstanceId string
+	Owner      string
+}
+
+func instanceKey(environment string, i *fargo.Instance) string {
+	return environment + "/" + strings.ToLower(i.InstanceId)
+}
+
+// wins reports whether the application takes precedence over the other one.
+// The oldest resource wins, ties are broken by resource name.
+func wins(app, other *Application) bool {
+	if !app.CreationTimestamp.Equal(other.CreationTimestamp) {
+		return app.CreationTimestamp.Before(other.CreationTimestamp)
+	}
+
+	return app.ResourceName < other.ResourceName
+}
+
+// OnChange sets a callback invoked with the name of every resource to reconcile again. Either its conflicts
+// changed, because it lost instances to another resource or a contended instance was released, or one of its
+// environments was unfrozen.
+func (s *Synchronizer) OnChange(f func(resourceName string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onChange = f
+}
+
+func (s *Synchronizer) notify(resourceName string) {
+	if s.onChange != nil {
+		go s.onChange(resourceName)
+	}
+}
+
+// resolveConflicts drops the instances of the application owned by a winning application,
+// and takes over the ones owned by losing applications. The registrations taken over are kept in Eureka.
+func (s *Synchronizer) resolveConflicts(n *Application) {
+	n.Conflicts = nil
+
+	var instances []*fargo.Instance
+	for _, i := range n.Instances {
+		ownerKey, owned := s.owners[instanceKey(n.Environment, i)]
+		owner := s.applications[ownerKey]
+
+		if !owned || ownerKey == n.key() || owner == nil {
+			instances = append(instances, i)
+		} else if wins(owner, n) {
+			s.log.Info("instance owned by another resource",
+				"environment", n.Environment, "instanceId", i.InstanceId, "resource", n.ResourceName, "owner", owner.ResourceName)
+
+			n.Conflicts = append(n.Conflicts, Conflict{InstanceId: i.InstanceId, Owner: owner.ResourceName})
+		} else {
+			s.log.Info("taking over instance from another resource",
+				"environment", n.Environment, "instanceId", i.InstanceId, "resource", n.ResourceName, "owner", owner.ResourceName)
+
+			owner.Instances = removeInstance(owner.Instances, i)
+			owner.Conflicts = append(owner.Conflicts, Conflict{InstanceId: i.InstanceId, Owner: n.ResourceName})
+			s.notify(owner.ResourceName)
+
+			instances = append(instances, i)
+		}
+	}
+
+	n.Instances = instances
+}
+
+// release removes the ownership of the instance and notifies the applications contending for it
+func (s *Synchronizer) release(app *Application, i *fargo.Instance) {
+	key := instanceKey(app.Environment, i)
+	if s.owners[key] != app.key() {
+		return
+	}
+
+	delete(s.owners, key)
+
+	for _, other := range s.applications {
+		for _, c := range other.Conflicts {
+			if other.Environment == app.Environment && strings.EqualFold(c.InstanceId, i.InstanceId) {
+				s.notify(other.ResourceName)
+				break
+			}
+		}
+	}
+}
+
+func removeInstance(instances []*fargo.Instance, i *fargo.Instance) []*fargo.Instance {
+	var result []*fargo.Instance
+	for _, o := range instances {
+		if !strings.EqualFold(o.InstanceId, i.InstanceId) {
+			result = append(result, o)
+		}
+	}
+
+	return result
+}
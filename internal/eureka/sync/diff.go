@@ -0,0 +1,42 @@
+package sync
+
+import (
+	"github.com/hudl/fargo"
+	"reflect"
+)
+
+// getInstancesToRegister returns the instances that are new or changed since the previous registration
+func getInstancesToRegister(old, new *Application) []*fargo.Instance {
+	previous := make(map[string]*fargo.Instance)
+	for _, o := range old.Instances {
+		previous[o.InstanceId] = o
+	}
+
+	var result []*fargo.Instance
+	for _, n := range new.Instances {
+		o, found := previous[n.InstanceId]
+		if !found || instanceChanged(o, n) || old.AsgNames[n.InstanceId] != new.AsgNames[n.InstanceId] {
+			result = append(result, n)
+		}
+	}
+
+	return result
+}
+
+// instanceChanged reports whether the registration of the instance differs in any field sent to Eureka
+func instanceChanged(old, new *fargo.Instance) bool {
+	return old.HostName != new.HostName ||
+		old.App != new.App ||
+		old.IPAddr != new.IPAddr ||
+		old.VipAddress != new.VipAddress ||
+		old.SecureVipAddress != new.SecureVipAddress ||
+		old.HomePageUrl != new.HomePageUrl ||
+		old.StatusPageUrl != new.StatusPageUrl ||
+		old.HealthCheckUrl != new.HealthCheckUrl ||
+		old.Port != new.Port ||
+		old.PortEnabled != new.PortEnabled ||
+		old.SecurePort != new.SecurePort ||
+		old.SecurePortEnabled != new.SecurePortEnabled ||
+		!reflect.DeepEqual(old.DataCenterInfo, new.DataCenterInfo) ||
+		!reflect.DeepEqual(old.Metadata.GetMap(), new.Metadata.GetMap())
+}
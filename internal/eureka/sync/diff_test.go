@@ -0,0 +1,143 @@
+package sync
+
+import (
+	"github.com/hudl/fargo"
+	"reflect"
+	"testing"
+)
+
+func diffInstance(id string, modify func(i *fargo.Instance)) *fargo.Instance {
+	i := &fargo.Instance{
+		InstanceId:     id,
+		HostName:       id + ".example.com",
+		App:            "ORDERS",
+		VipAddress:     "orders",
+		HomePageUrl:    "https://" + id + ".example.com/",
+		StatusPageUrl:  "https://" + id + ".example.com/info",
+		HealthCheckUrl: "https://" + id + ".example.com/health",
+		Port:           80,
+		PortEnabled:    true,
+		DataCenterInfo: fargo.DataCenterInfo{Name: fargo.MyOwn},
+	}
+	i.SetMetadataString("zone", "default-zone")
+
+	if modify != nil {
+		modify(i)
+	}
+
+	return i
+}
+
+func TestInstanceChanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(i *fargo.Instance)
+		want   bool
+	}{
+		{
+			name: "unchanged",
+			want: false,
+		},
+		{
+			name:   "status only",
+			modify: func(i *fargo.Instance) { i.Status = fargo.DOWN },
+			want:   false,
+		},
+		{
+			name:   "host name",
+			modify: func(i *fargo.Instance) { i.HostName = "other.example.com" },
+			want:   true,
+		},
+		{
+			name:   "health check url",
+			modify: func(i *fargo.Instance) { i.HealthCheckUrl = "https://orders-1.example.com/actuator/health" },
+			want:   true,
+		},
+		{
+			name:   "secure port",
+			modify: func(i *fargo.Instance) { i.SecurePort, i.SecurePortEnabled = 443, true },
+			want:   true,
+		},
+		{
+			name:   "data center",
+			modify: func(i *fargo.Instance) { i.DataCenterInfo = fargo.DataCenterInfo{Name: fargo.Amazon} },
+			want:   true,
+		},
+		{
+			name:   "metadata",
+			modify: func(i *fargo.Instance) { i.SetMetadataString("zone", "eu-west-1a") },
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := instanceChanged(diffInstance("orders-1", nil), diffInstance("orders-1", tt.modify)); got != tt.want {
+				t.Errorf("instanceChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetInstancesToRegister(t *testing.T) {
+	changed := func(i *fargo.Instance) { i.StatusPageUrl = "https://orders.example.com/status" }
+
+	tests := []struct {
+		name string
+		old  *Application
+		new  *Application
+		want []string
+	}{
+		{
+			name: "first registration",
+			old:  &Application{},
+			new:  &Application{Instances: []*fargo.Instance{diffInstance("orders-1", nil), diffInstance("orders-2", nil)}},
+			want: []string{"orders-1", "orders-2"},
+		},
+		{
+			name: "unchanged",
+			old:  &Application{Instances: []*fargo.Instance{diffInstance("orders-1", nil)}},
+			new:  &Application{Instances: []*fargo.Instance{diffInstance("orders-1", nil)}},
+			want: nil,
+		},
+		{
+			name: "one changed, one added",
+			old:  &Application{Instances: []*fargo.Instance{diffInstance("orders-1", nil), diffInstance("orders-2", nil)}},
+			new: &Application{Instances: []*fargo.Instance{
+				diffInstance("orders-1", nil), diffInstance("orders-2", changed), diffInstance("orders-3", nil),
+			}},
+			want: []string{"orders-2", "orders-3"},
+		},
+		{
+			name: "removed",
+			old:  &Application{Instances: []*fargo.Instance{diffInstance("orders-1", nil), diffInstance("orders-2", nil)}},
+			new:  &Application{Instances: []*fargo.Instance{diffInstance("orders-1", nil)}},
+			want: nil,
+		},
+		{
+			name: "asg name changed",
+			old: &Application{
+				Instances: []*fargo.Instance{diffInstance("orders-1", nil)},
+				AsgNames:  map[string]string{"orders-1": "orders-v1"},
+			},
+			new: &Application{
+				Instances: []*fargo.Instance{diffInstance("orders-1", nil)},
+				AsgNames:  map[string]string{"orders-1": "orders-v2"},
+			},
+			want: []string{"orders-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, i := range getInstancesToRegister(tt.old, tt.new) {
+				got = append(got, i.InstanceId)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getInstancesToRegister() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package sync
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+// AllEnvironments freezes every environment
+const AllEnvironments = "*"
+
+var frozenEnvironments = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "eurek8s_frozen",
+		Help: "Whether the writes to the environment are frozen (1) or not (0)",
+	},
+	[]string{"environment"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(frozenEnvironments)
+}
+
+// Freeze stops the writes to environments, registrations and deregistrations are queued until it's lifted
+type Freeze struct {
+	// Environments frozen, AllEnvironments freezes them all
+	Environments []string
+	// PauseHeartbeats pauses the heartbeats of the frozen environments as well
+	PauseHeartbeats bool
+}
+
+func (f Freeze) freezes(environment string) bool {
+	return containsEnvironment(f.Environments, environment)
+}
+
+// containsEnvironment reports whether the environment is listed, AllEnvironments listing them all
+func containsEnvironment(environments []string, environment string) bool {
+	for _, e := range environments {
+		if e == environment || e == AllEnvironments {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetFreeze sets the freeze of a source, an environment being frozen as long as any source freezes it.
+// Lifting the freeze of an environment replays its queued operations and re-queues its resources to catch up.
+func (s *Synchronizer) SetFreeze(source string, f Freeze) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	environments := s.client.Environments()
+	wasFrozen := make(map[string]bool)
+	for _, e := range environments {
+		wasFrozen[e] = s.frozen(e)
+	}
+
+	if len(f.Environments) == 0 {
+		delete(s.freezes, source)
+	} else {
+		s.freezes[source] = f
+	}
+
+	now := time.Now()
+	for _, e := range environments {
+		frozen := s.frozen(e)
+		if frozen {
+			frozenEnvironments.WithLabelValues(e).Set(1)
+		} else {
+			frozenEnvironments.WithLabelValues(e).Set(0)
+		}
+
+		if frozen == wasFrozen[e] {
+			continue
+		}
+
+		if frozen {
+			s.log.Info("environment frozen, queueing registrations and deregistrations", "environment", e, "source", source)
+			continue
+		}
+
+		s.log.Info("environment unfrozen, catching up", "environment", e, "source", source)
+		for _, op := range s.outbox {
+			if op.Environment == e {
+				op.NextAttempt = now
+			}
+		}
+
+		notified := make(map[string]bool)
+		for _, app := range s.applications {
+			if app.Environment == e && !notified[app.ResourceName] {
+				notified[app.ResourceName] = true
+				s.notify(app.ResourceName)
+			}
+		}
+	}
+}
+
+// Frozen reports whether the writes to the environment are frozen
+func (s *Synchronizer) Frozen(environment string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.frozen(environment)
+}
+
+func (s *Synchronizer) frozen(environment string) bool {
+	for _, f := range s.freezes {
+		if f.freezes(environment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Synchronizer) heartbeatsPaused(environment string) bool {
+	for _, f := range s.freezes {
+		if f.PauseHeartbeats && f.freezes(environment) {
+			return true
+		}
+	}
+
+	return false
+}
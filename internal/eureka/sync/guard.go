@@ -0,0 +1,195 @@
+package sync
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+const (
+	defaultGuardWindow = 5 * time.Minute
+	// small environments would trip MaxPercent with their first deregistration
+	defaultGuardMinInstances = 3
+)
+
+// ErrDeregistrationHeld is returned for deregistrations held back by the DeregistrationGuard of their environment
+var ErrDeregistrationHeld = errors.New("deregistration held back by the mass deregistration guard")
+
+var deregistrationGuardTripped = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "eurek8s_deregistration_guard_tripped",
+		Help: "Whether the deregistrations of the environment are held back (1) or not (0)",
+	},
+	[]string{"environment"},
+)
+
+func init() {
+	metrics.Registry.MustRegister(deregistrationGuardTripped)
+}
+
+// DeregistrationGuard holds back the deregistrations of an environment once more than MaxInstances, or more than
+// MaxPercent of its instances, are deregistered within Window. Zero limits are ignored. MaxPercent only applies
+// beyond MinInstances deregistrations.
+type DeregistrationGuard struct {
+	MaxInstances int
+	MaxPercent   int
+	MinInstances int
+	Window       time.Duration
+}
+
+// exceeded reports whether n deregistrations out of total instances within the window exceed the guard
+func (g DeregistrationGuard) exceeded(n, total int) bool {
+	if g.MaxInstances > 0 && n > g.MaxInstances {
+		return true
+	}
+
+	return g.MaxPercent > 0 && n > g.MinInstances && n*100 > g.MaxPercent*total
+}
+
+type guardState struct {
+	deregistrations []time.Time
+	tripped         bool
+}
+
+// SetDeregistrationGuard guards the deregistrations of the environment
+func (s *Synchronizer) SetDeregistrationGuard(environment string, g DeregistrationGuard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if g.Window == 0 {
+		g.Window = defaultGuardWindow
+	}
+	if g.MinInstances == 0 {
+		g.MinInstances = defaultGuardMinInstances
+	}
+
+	s.guards[environment] = g
+	s.guardStates[environment] = &guardState{}
+	deregistrationGuardTripped.WithLabelValues(environment).Set(0)
+}
+
+// AllowMassDeregistration lets the deregistrations of the resource bypass the guards
+func (s *Synchronizer) AllowMassDeregistration(resourceName string, allow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if allow == s.allowedResources[resourceName] {
+		return
+	}
+
+	if !allow {
+		delete(s.allowedResources, resourceName)
+		return
+	}
+
+	s.log.Info("mass deregistration allowed", "resource", resourceName)
+	s.allowedResources[resourceName] = true
+	s.releaseHeld(func(op *Operation) bool { return op.ResourceName == resourceName })
+}
+
+// AllowMassDeregistrations lets every deregistration of the environments bypass their guard,
+// AllEnvironments allowing them all
+func (s *Synchronizer) AllowMassDeregistrations(environments []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.allowedEnvironments = environments
+	if len(environments) > 0 {
+		s.log.Info("mass deregistrations allowed", "environments", environments)
+		s.releaseHeld(func(op *Operation) bool { return containsEnvironment(environments, op.Environment) })
+	}
+}
+
+// HeldDeregistrations returns the number of deregistrations of the resource held back by a guard
+func (s *Synchronizer) HeldDeregistrations(resourceName string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var held int
+	for _, op := range s.outbox {
+		if op.Held && op.ResourceName == resourceName {
+			held++
+		}
+	}
+
+	return held
+}
+
+// holdDeregistration reports whether the next deregistration of the resource exceeds the guard of the environment
+func (s *Synchronizer) holdDeregistration(resourceName, environment string) bool {
+	g, ok := s.guards[environment]
+	if !ok || s.allowedResources[resourceName] || containsEnvironment(s.allowedEnvironments, environment) {
+		return false
+	}
+
+	st := s.guardStates[environment]
+	if st.tripped {
+		return true
+	}
+
+	st.prune(time.Now().Add(-g.Window))
+
+	// the instances registered at the start of the window
+	total := len(st.deregistrations)
+	for _, app := range s.applications {
+		if app.Environment == environment {
+			total += len(app.Instances)
+		}
+	}
+
+	if g.exceeded(len(st.deregistrations)+1, total) {
+		s.log.Error(ErrDeregistrationHeld, "too many deregistrations, holding back the next ones",
+			"environment", environment, "deregistrations", len(st.deregistrations), "window", g.Window)
+		st.tripped = true
+		deregistrationGuardTripped.WithLabelValues(environment).Set(1)
+		return true
+	}
+
+	return false
+}
+
+// recordDeregistration counts a deregistration towards the guard of the environment
+func (s *Synchronizer) recordDeregistration(environment string) {
+	if st, ok := s.guardStates[environment]; ok {
+		st.deregistrations = append(st.deregistrations, time.Now())
+	}
+}
+
+// resetGuards lifts the guards without held deregistrations left, i.e once they are allowed or registered again
+func (s *Synchronizer) resetGuards() {
+	held := make(map[string]bool)
+	for _, op := range s.outbox {
+		if op.Held {
+			held[op.Environment] = true
+		}
+	}
+
+	for environment, st := range s.guardStates {
+		if st.tripped && !held[environment] {
+			s.log.Info("no deregistration held back anymore, lifting the guard", "environment", environment)
+			st.tripped = false
+			st.deregistrations = nil
+			deregistrationGuardTripped.WithLabelValues(environment).Set(0)
+		}
+	}
+}
+
+// releaseHeld replays the held deregistrations matching the filter with the next tick
+func (s *Synchronizer) releaseHeld(filter func(op *Operation) bool) {
+	now := time.Now()
+	for _, op := range s.outbox {
+		if op.Held && filter(op) {
+			op.NextAttempt = now
+		}
+	}
+}
+
+func (st *guardState) prune(since time.Time) {
+	var i int
+	for i < len(st.deregistrations) && st.deregistrations[i].Before(since) {
+		i++
+	}
+
+	st.deregistrations = st.deregistrations[i:]
+}
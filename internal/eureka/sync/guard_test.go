@@ -0,0 +1,114 @@
+package sync
+
+import (
+	"github.com/go-logr/logr"
+	"testing"
+)
+
+func TestDeregistrationGuardExceeded(t *testing.T) {
+	tests := []struct {
+		name  string
+		guard DeregistrationGuard
+		n     int
+		total int
+		want  bool
+	}{
+		{
+			name:  "no limit",
+			guard: DeregistrationGuard{},
+			n:     100,
+			total: 100,
+			want:  false,
+		},
+		{
+			name:  "within max instances",
+			guard: DeregistrationGuard{MaxInstances: 5},
+			n:     5,
+			total: 100,
+			want:  false,
+		},
+		{
+			name:  "beyond max instances",
+			guard: DeregistrationGuard{MaxInstances: 5},
+			n:     6,
+			total: 100,
+			want:  true,
+		},
+		{
+			name:  "max instances ignores min instances",
+			guard: DeregistrationGuard{MaxInstances: 1, MinInstances: 3},
+			n:     2,
+			total: 100,
+			want:  true,
+		},
+		{
+			name:  "within max percent",
+			guard: DeregistrationGuard{MaxPercent: 20, MinInstances: 3},
+			n:     20,
+			total: 100,
+			want:  false,
+		},
+		{
+			name:  "beyond max percent",
+			guard: DeregistrationGuard{MaxPercent: 20, MinInstances: 3},
+			n:     21,
+			total: 100,
+			want:  true,
+		},
+		{
+			name:  "single deregistration of a small environment",
+			guard: DeregistrationGuard{MaxPercent: 20, MinInstances: 3},
+			n:     1,
+			total: 4,
+			want:  false,
+		},
+		{
+			name:  "small environment at min instances",
+			guard: DeregistrationGuard{MaxPercent: 20, MinInstances: 3},
+			n:     3,
+			total: 4,
+			want:  false,
+		},
+		{
+			name:  "small environment beyond min instances",
+			guard: DeregistrationGuard{MaxPercent: 20, MinInstances: 3},
+			n:     4,
+			total: 4,
+			want:  true,
+		},
+		{
+			name:  "without min instances",
+			guard: DeregistrationGuard{MaxPercent: 20},
+			n:     1,
+			total: 4,
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.guard.exceeded(tt.n, tt.total); got != tt.want {
+				t.Errorf("exceeded(%d, %d) = %v, want %v", tt.n, tt.total, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHoldDeregistrationSmallEnvironment(t *testing.T) {
+	s := New(nil, logr.Discard())
+	s.SetDeregistrationGuard("qa", DeregistrationGuard{MaxPercent: 20})
+	app := testApplication("default/orders", "a", "b", "c", "d")
+	s.applications[app.key()] = app
+
+	// the instances are deregistered one after the other, the guard trips with the 4th one
+	for i, want := range []bool{false, false, false, true} {
+		if got := s.holdDeregistration("default/orders", "qa"); got != want {
+			t.Fatalf("deregistration %d: holdDeregistration() = %v, want %v", i+1, got, want)
+		}
+
+		if !want {
+			s.recordDeregistration("qa")
+			app.Instances = app.Instances[1:]
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	eurekaclient "github.com/eurek8s/controller/internal/eureka/client"
+	"github.com/hudl/fargo"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	OperationRegister   OperationType = "register"
+	OperationDeregister OperationType = "deregister"
+
+	outboxDataKey = "operations"
+
+	minReplayBackoff  = 10 * time.Second
+	maxReplaysPerTick = 50
+	maxReplayBackoff  = 5 * time.Minute
+
+	// operations older than this are given up, i.e when Eureka or the environment is gone for good
+	maxOperationAge = 24 * time.Hour
+
+	// the outbox is trimmed beyond this many operations, so it fits into a ConfigMap
+	maxPendingOperations = 500
+	// ConfigMaps are limited to 1MiB, the rest is left for the metadata
+	maxOutboxDataSize = 960 * 1024
+
+	persistRetryInterval = 10 * time.Second
+	// the Eureka calls wait at most this long for their operations to be saved
+	flushTimeout = 10 * time.Second
+
+	dropReasonExpired    = "expired"
+	dropReasonOutboxFull = "outbox_full"
+)
+
+var (
+	// ErrOutboxTooLarge is returned when the pending operations don't fit into the store
+	ErrOutboxTooLarge = errors.New("too many pending operations to persist")
+	// ErrOperationExpired is logged for the operations given up after maxOperationAge
+	ErrOperationExpired = errors.New("pending operation given up after 24 hours")
+)
+
+var (
+	pendingOperations = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eurek8s_pending_operations",
+			Help: "Number of Eureka operations waiting to be replayed",
+		},
+		[]string{"environment", "type"},
+	)
+	droppedOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eurek8s_dropped_operations",
+			Help: "Number of pending Eureka operations given up, because they expired or the outbox is full",
+		},
+		[]string{"environment", "type", "reason"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(pendingOperations, droppedOperations)
+}
+
+// OperationType is the Eureka call of an Operation
+type OperationType string
+
+// Operation is a Eureka call recorded before being made, and kept until Eureka confirms it
+type Operation struct {
+	Type         OperationType   `json:"type"`
+	ResourceName string          `json:"resourceName"`
+	Environment  string          `json:"environment"`
+	Instance     *fargo.Instance `json:"instance"`
+	AsgName      string          `json:"asgName,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	Attempts     int             `json:"attempts,omitempty"`
+	NextAttempt  time.Time       `json:"nextAttempt,omitempty"`
+	LastError    string          `json:"lastError,omitempty"`
+	// Held is a deregistration held back by a DeregistrationGuard until allowed
+	Held bool `json:"held,omitempty"`
+}
+
+// OutboxStore persists the pending operations so they survive restarts
+type OutboxStore interface {
+	Load(ctx context.Context) ([]*Operation, error)
+	Save(ctx context.Context, operations []*Operation) error
+}
+
+// ConfigMapStore is an OutboxStore keeping the pending operations in a ConfigMap
+type ConfigMapStore struct {
+	Client client.Client
+	// Reader is used to load the operations before the cache of the manager is started
+	Reader client.Reader
+	Key    types.NamespacedName
+}
+
+func (c *ConfigMapStore) Load(ctx context.Context) ([]*Operation, error) {
+	var cm corev1.ConfigMap
+	if err := c.Reader.Get(ctx, c.Key, &cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+
+	var operations []*Operation
+	if data := cm.Data[outboxDataKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &operations); err != nil {
+			return nil, err
+		}
+	}
+
+	return operations, nil
+}
+
+func (c *ConfigMapStore) Save(ctx context.Context, operations []*Operation) error {
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > maxOutboxDataSize {
+		return ErrOutboxTooLarge
+	}
+
+	var cm corev1.ConfigMap
+	if err := c.Reader.Get(ctx, c.Key, &cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		cm.Namespace, cm.Name = c.Key.Namespace, c.Key.Name
+		cm.Data = map[string]string{outboxDataKey: string(data)}
+
+		return c.Client.Create(ctx, &cm)
+	}
+
+	if cm.Data == nil {
+		cm.Data = make(map[string]string)
+	}
+	cm.Data[outboxDataKey] = string(data)
+
+	return c.Client.Update(ctx, &cm)
+}
+
+// OutboxPersister loads the operations pending in the store, then persists the outbox into it. It runs on the
+// leader only, so the replicas never overwrite the operations of each other.
+type OutboxPersister struct {
+	Synchronizer *Synchronizer
+	Store        OutboxStore
+
+	// saves are serialized, the changes made meanwhile being coalesced into the next one
+	mu sync.Mutex
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (p *OutboxPersister) NeedLeaderElection() bool {
+	return true
+}
+
+// Start loads the pending operations and persists the changes of the outbox until the context is done. The operations
+// are saved by the synchronizer before their Eureka calls are made, and by Start once they are settled.
+func (p *OutboxPersister) Start(ctx context.Context) error {
+	s := p.Synchronizer
+
+	operations, err := p.Store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	s.load(operations)
+
+	s.mu.Lock()
+	s.persister = p
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(persistRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.persister = nil
+			s.mu.Unlock()
+
+			// last chance to keep the operations confirmed meanwhile
+			p.save(context.Background())
+			return nil
+		case <-s.persistChan:
+		case <-ticker.C:
+		}
+
+		p.save(ctx)
+	}
+}
+
+// save persists a copy of the outbox if it changed since the last save, the changes are saved again on failure
+func (p *OutboxPersister) save(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.Synchronizer
+
+	s.mu.Lock()
+	if !s.outboxUnsaved {
+		s.mu.Unlock()
+		return
+	}
+
+	operations := make([]*Operation, 0, len(s.outbox))
+	for _, op := range s.outbox {
+		c := *op
+		operations = append(operations, &c)
+	}
+	s.outboxUnsaved = false
+	s.mu.Unlock()
+
+	if err := p.Store.Save(ctx, operations); err != nil {
+		s.log.Error(err, "unable to persist pending operations", "count", len(operations))
+
+		s.mu.Lock()
+		s.outboxUnsaved = true
+		s.mu.Unlock()
+	}
+}
+
+// load adds the operations loaded from the store to the outbox, unless superseded since the start
+func (s *Synchronizer) load(operations []*Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, op := range operations {
+		if op.Instance == nil {
+			continue
+		}
+
+		key := instanceKey(op.Environment, op.Instance)
+		if _, ok := s.outbox[key]; ok {
+			continue
+		}
+
+		op.Instance.UniqueID = uniqueID
+		s.outbox[key] = op
+	}
+
+	s.trimOutbox()
+	s.outboxChanged = true
+	s.persist()
+
+	s.log.Info("loaded pending operations", "count", len(operations))
+}
+
+// intend records the operations in the outbox before calling Eureka. An operation supersedes
+// any operation pending for the same instance.
+func (s *Synchronizer) intend(t OperationType, app *Application, instances []*fargo.Instance) {
+	now := time.Now()
+	for _, i := range instances {
+		s.outbox[instanceKey(app.Environment, i)] = &Operation{
+			Type:         t,
+			ResourceName: app.ResourceName,
+			Environment:  app.Environment,
+			Instance:     i,
+			AsgName:      app.AsgNames[i.InstanceId],
+			CreatedAt:    now,
+			NextAttempt:  now.Add(minReplayBackoff),
+		}
+	}
+
+	s.trimOutbox()
+	s.outboxChanged = true
+}
+
+// trimOutbox drops the oldest operations beyond maxPendingOperations, registrations first since they are sent again
+// on the next change of their resource, while a dropped deregistration leaves its instance registered
+func (s *Synchronizer) trimOutbox() {
+	for len(s.outbox) > maxPendingOperations {
+		var oldestKey string
+		var oldest *Operation
+		for key, op := range s.outbox {
+			if oldest == nil || (op.Type == OperationRegister && oldest.Type != OperationRegister) ||
+				(op.Type == oldest.Type && op.CreatedAt.Before(oldest.CreatedAt)) {
+				oldestKey, oldest = key, op
+			}
+		}
+
+		s.drop(oldestKey, oldest, dropReasonOutboxFull, ErrOutboxTooLarge)
+	}
+}
+
+// drop gives up the pending operation, a dropped deregistration leaving its instance registered until its lease
+// expires, unless it is still sent heartbeats
+func (s *Synchronizer) drop(key string, op *Operation, reason string, err error) {
+	s.log.Error(err, "giving up pending operation", "type", op.Type, "resource", op.ResourceName,
+		"environment", op.Environment, "instanceId", op.Instance.InstanceId, "reason", reason, "lastError", op.LastError)
+	droppedOperations.WithLabelValues(op.Environment, string(op.Type), reason).Inc()
+	delete(s.outbox, key)
+	s.outboxChanged = true
+}
+
+// settle drops the operation of the instance once Eureka confirmed it, or schedules its replay. Operations
+// superseded while waiting for the rate limit are left alone.
+func (s *Synchronizer) settle(environment string, i *fargo.Instance, err error) {
+	key := instanceKey(environment, i)
+	op, ok := s.outbox[key]
+	if !ok || op.Instance != i {
+		return
+	}
+
+	s.outboxChanged = true
+
+	if err == nil {
+		delete(s.outbox, key)
+		return
+	}
+
+	op.LastError = err.Error()
+
+	// held deregistrations are replayed once allowed, or given up with the other operations
+	op.Held = errors.Is(err, ErrDeregistrationHeld)
+	if op.Held {
+		op.NextAttempt = time.Now().Add(maxReplayBackoff)
+		return
+	}
+
+	op.Attempts++
+
+	backoff := minReplayBackoff
+	for n := 1; n < op.Attempts && backoff < maxReplayBackoff; n++ {
+		backoff *= 2
+	}
+	if backoff > maxReplayBackoff {
+		backoff = maxReplayBackoff
+	}
+	op.NextAttempt = time.Now().Add(backoff)
+}
+
+// revokeRegistrations turns the registrations pending for the resource into deregistrations,
+// since they may have reached Eureka before being confirmed
+func (s *Synchronizer) revokeRegistrations(resourceName string, revoked func(environment string) bool) {
+	for _, op := range s.outbox {
+		if op.Type == OperationRegister && op.ResourceName == resourceName && revoked(op.Environment) {
+			op.Type = OperationDeregister
+			op.Attempts = 0
+			op.NextAttempt = time.Now()
+			s.outboxChanged = true
+		}
+	}
+}
+
+// replay retries the pending operations due, until Eureka confirms them
+func (s *Synchronizer) replay() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var replayed int
+	for key, op := range s.outbox {
+		if now.Sub(op.CreatedAt) > maxOperationAge {
+			s.drop(key, op, dropReasonExpired, ErrOperationExpired)
+			continue
+		}
+
+		if now.Before(op.NextAttempt) || s.frozen(op.Environment) {
+			continue
+		}
+
+		// operations are replayed in batches, to catch up with a lifted freeze without flooding Eureka
+		if replayed >= maxReplaysPerTick {
+			break
+		}
+		replayed++
+
+		priority := eurekaclient.PriorityLow
+		if op.Type == OperationDeregister {
+			priority = eurekaclient.PriorityHigh
+		}
+		s.throttle(op.Environment, priority)
+
+		// the operation has been superseded or confirmed while waiting for the rate limit
+		if s.outbox[key] != op {
+			continue
+		}
+
+		// the instance has been registered again since, by this resource or another one
+		if _, owned := s.owners[key]; owned && op.Type == OperationDeregister {
+			delete(s.outbox, key)
+			s.outboxChanged = true
+			continue
+		}
+
+		app := &Application{ResourceName: op.ResourceName, Environment: op.Environment, Name: op.Instance.App}
+
+		var err error
+		if op.Type == OperationRegister {
+			s.log.Info("replaying instance registration", "environment", op.Environment, "instanceId", op.Instance.InstanceId)
+			err = s.client.ReregisterAsgInstance(op.Environment, op.Instance, op.AsgName)
+		} else {
+			s.log.Info("replaying instance deregistration", "environment", op.Environment, "instanceId", op.Instance.InstanceId)
+			err = s.sendDeregistration(app, op.Instance)
+		}
+
+		s.settle(op.Environment, op.Instance, err)
+	}
+
+	s.resetGuards()
+	s.persist()
+}
+
+// persist schedules the save of the outbox by the OutboxPersister, if any and changed. Saves requested while
+// another one is pending are coalesced.
+func (s *Synchronizer) persist() {
+	if !s.outboxChanged {
+		return
+	}
+
+	s.updatePendingOperations()
+	s.outboxChanged = false
+	s.outboxUnsaved = true
+
+	select {
+	case s.persistChan <- struct{}{}:
+	default:
+	}
+}
+
+// flush saves the outbox before the Eureka calls recorded in it are made, so their operations survive a crash. The
+// lock is released while saving. A failing save is logged and the calls are made anyway, the outbox being saved
+// again later.
+func (s *Synchronizer) flush() {
+	s.persist()
+
+	p := s.persister
+	if p == nil || !s.outboxUnsaved {
+		return
+	}
+
+	s.mu.Unlock()
+	defer s.mu.Lock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	p.save(ctx)
+}
+
+func (s *Synchronizer) updatePendingOperations() {
+	pendingOperations.Reset()
+	for _, op := range s.outbox {
+		pendingOperations.WithLabelValues(op.Environment, string(op.Type)).Inc()
+	}
+}
+
+func uniqueID(i fargo.Instance) string {
+	return strings.ToLower(i.Id())
+}
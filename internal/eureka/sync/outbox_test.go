@@ -0,0 +1,251 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/eurek8s/controller/internal/eureka/client"
+	"github.com/go-logr/logr"
+	"github.com/hudl/fargo"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testOperation(opType OperationType, instanceID string, createdAt time.Time) *Operation {
+	return &Operation{
+		Type:         opType,
+		ResourceName: "default/orders",
+		Environment:  "qa",
+		Instance:     &fargo.Instance{UniqueID: uniqueID, InstanceId: instanceID, App: "ORDERS"},
+		CreatedAt:    createdAt,
+		NextAttempt:  createdAt.Add(minReplayBackoff),
+	}
+}
+
+func TestSettleBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     time.Duration
+		held     bool
+	}{
+		{
+			name:     "first failure",
+			attempts: 0,
+			err:      errors.New("connection refused"),
+			want:     minReplayBackoff,
+		},
+		{
+			name:     "second failure",
+			attempts: 1,
+			err:      errors.New("connection refused"),
+			want:     2 * minReplayBackoff,
+		},
+		{
+			name:     "fourth failure",
+			attempts: 3,
+			err:      errors.New("connection refused"),
+			want:     8 * minReplayBackoff,
+		},
+		{
+			name:     "capped",
+			attempts: 10,
+			err:      errors.New("connection refused"),
+			want:     maxReplayBackoff,
+		},
+		{
+			name:     "held deregistration",
+			attempts: 0,
+			err:      fmt.Errorf("deregistering instance: %w", ErrDeregistrationHeld),
+			want:     maxReplayBackoff,
+			held:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(nil, logr.Discard())
+			op := testOperation(OperationRegister, "orders-1", time.Now())
+			op.Attempts = tt.attempts
+			s.outbox[instanceKey(op.Environment, op.Instance)] = op
+
+			before := time.Now()
+			s.settle(op.Environment, op.Instance, tt.err)
+
+			if backoff := op.NextAttempt.Sub(before); backoff < tt.want || backoff > tt.want+time.Second {
+				t.Errorf("backoff = %s, want %s", backoff, tt.want)
+			}
+
+			if op.Held != tt.held {
+				t.Errorf("held = %v, want %v", op.Held, tt.held)
+			}
+
+			if op.LastError != tt.err.Error() {
+				t.Errorf("last error = %q, want %q", op.LastError, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestSettleConfirmed(t *testing.T) {
+	s := New(nil, logr.Discard())
+	op := testOperation(OperationRegister, "orders-1", time.Now())
+	key := instanceKey(op.Environment, op.Instance)
+	s.outbox[key] = op
+
+	// a confirmation of a superseded operation keeps the pending one
+	s.settle(op.Environment, &fargo.Instance{UniqueID: uniqueID, InstanceId: "orders-1"}, nil)
+	if _, ok := s.outbox[key]; !ok {
+		t.Fatal("superseded confirmation removed the pending operation")
+	}
+
+	s.settle(op.Environment, op.Instance, nil)
+	if _, ok := s.outbox[key]; ok {
+		t.Fatal("confirmed operation still pending")
+	}
+}
+
+func TestReplayGivesUpExpiredOperations(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		want      bool
+	}{
+		{
+			name:      "recent",
+			createdAt: now.Add(-time.Minute),
+			want:      true,
+		},
+		{
+			name:      "almost expired",
+			createdAt: now.Add(-maxOperationAge + time.Minute),
+			want:      true,
+		},
+		{
+			name:      "expired",
+			createdAt: now.Add(-maxOperationAge - time.Minute),
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(nil, logr.Discard())
+			op := testOperation(OperationRegister, "orders-1", tt.createdAt)
+			// not due, so nothing is sent to Eureka
+			op.NextAttempt = now.Add(time.Hour)
+			key := instanceKey(op.Environment, op.Instance)
+			s.outbox[key] = op
+
+			s.replay()
+
+			if _, ok := s.outbox[key]; ok != tt.want {
+				t.Errorf("pending = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimOutbox(t *testing.T) {
+	s := New(nil, logr.Discard())
+	now := time.Now()
+
+	// the oldest operation is a deregistration, it is kept over the registrations
+	deregistration := testOperation(OperationDeregister, "orders-0", now.Add(-time.Hour))
+	s.outbox[instanceKey("qa", deregistration.Instance)] = deregistration
+	for n := 1; n <= maxPendingOperations; n++ {
+		op := testOperation(OperationRegister, fmt.Sprintf("orders-%d", n), now.Add(time.Duration(n)*time.Second))
+		s.outbox[instanceKey("qa", op.Instance)] = op
+	}
+
+	s.trimOutbox()
+
+	if len(s.outbox) != maxPendingOperations {
+		t.Fatalf("pending operations = %d, want %d", len(s.outbox), maxPendingOperations)
+	}
+
+	if _, ok := s.outbox[instanceKey("qa", deregistration.Instance)]; !ok {
+		t.Error("deregistration dropped before the registrations")
+	}
+
+	if _, ok := s.outbox[instanceKey("qa", &fargo.Instance{InstanceId: "orders-1"})]; ok {
+		t.Error("oldest registration kept")
+	}
+}
+
+// memoryStore is an OutboxStore keeping the last saved operations
+type memoryStore struct {
+	mu         sync.Mutex
+	operations []*Operation
+}
+
+func (m *memoryStore) Load(context.Context) ([]*Operation, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) Save(_ context.Context, operations []*Operation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.operations = operations
+	return nil
+}
+
+func (m *memoryStore) saved(instanceID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, op := range m.operations {
+		if op.Instance.InstanceId == instanceID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestOperationSavedBeforeEurekaCall(t *testing.T) {
+	store := &memoryStore{}
+
+	var mu sync.Mutex
+	var unsaved []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && !store.saved("orders-1") {
+			mu.Lock()
+			unsaved = append(unsaved, r.URL.Path)
+			mu.Unlock()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := New(client.New(map[string][]string{"qa": {server.URL}}), logr.Discard())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = (&OutboxPersister{Synchronizer: s, Store: store}).Start(ctx)
+	}()
+
+	for started := false; !started; time.Sleep(10 * time.Millisecond) {
+		s.mu.Lock()
+		started = s.persister != nil
+		s.mu.Unlock()
+	}
+
+	if err := s.RegisterApplicationSync(testApplication("default/orders", "orders-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(unsaved) > 0 {
+		t.Errorf("registered before the operation was saved: %v", unsaved)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/hudl/fargo"
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -54,6 +56,20 @@ var (
 		},
 		[]string{"environment", "appName", "appInstance"},
 	)
+	totalStatusUpdates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eurek8s_total_status_updates",
+			Help: "Number of status overrides processed",
+		},
+		[]string{"environment", "appName", "appInstance"},
+	)
+	statusUpdateFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eurek8s_status_update_failures",
+			Help: "Number of failed status overrides",
+		},
+		[]string{"environment", "appName", "appInstance"},
+	)
 )
 
 func init() {
@@ -61,12 +77,31 @@ func init() {
 		totalHeartbeats, heartbeatFailures,
 		totalRegistrations, registrationFailures,
 		totalDeregistrations, deregistrationFailures,
+		totalStatusUpdates, statusUpdateFailures,
 	)
 }
 
 type Synchronizer struct {
-	client         *client.EurekaClient
-	applications   map[string]*Application
+	client       *client.EurekaClient
+	mu           sync.Mutex
+	applications map[string]*Application
+	owners       map[string]string
+	onChange     func(resourceName string)
+	freezes      map[string]Freeze
+	guards       map[string]DeregistrationGuard
+	guardStates  map[string]*guardState
+	// resources and environments allowed to bypass the guards
+	allowedResources    map[string]bool
+	allowedEnvironments []string
+	outbox              map[string]*Operation
+	outboxChanged       bool
+	// the outbox changed since it was last saved by the OutboxPersister
+	outboxUnsaved bool
+	persistChan   chan struct{}
+	// persister saves the outbox before the Eureka calls, set on the leader only
+	persister *OutboxPersister
+	// instances lost by Eureka being registered again, by instance key
+	reregistering  map[string]bool
 	registerChan   chan *Application
 	deregisterChan chan string
 	log            logr.Logger
@@ -74,11 +109,19 @@ type Synchronizer struct {
 
 func New(client *client.EurekaClient, log logr.Logger) *Synchronizer {
 	return &Synchronizer{
-		client:         client,
-		applications:   make(map[string]*Application),
-		registerChan:   make(chan *Application),
-		deregisterChan: make(chan string),
-		log:            log,
+		client:           client,
+		applications:     make(map[string]*Application),
+		owners:           make(map[string]string),
+		outbox:           make(map[string]*Operation),
+		freezes:          make(map[string]Freeze),
+		guards:           make(map[string]DeregistrationGuard),
+		guardStates:      make(map[string]*guardState),
+		allowedResources: make(map[string]bool),
+		reregistering:    make(map[string]bool),
+		persistChan:      make(chan struct{}, 1),
+		registerChan:     make(chan *Application),
+		deregisterChan:   make(chan string),
+		log:              log,
 	}
 }
 
@@ -88,7 +131,7 @@ func (s *Synchronizer) goProcess() {
 	for {
 		select {
 		case _ = <-tickChan:
-			s.heartbeat()
+			s.replay()
 		case application := <-s.registerChan:
 			if err := s.RegisterApplicationSync(application); err != nil {
 				s.log.Error(err, "Error trying to Register App (Channel)")
@@ -99,6 +142,13 @@ func (s *Synchronizer) goProcess() {
 	}
 }
 
+// goHeartbeat sends the heartbeats apart from the writes, so they never wait for the rate limits
+func (s *Synchronizer) goHeartbeat() {
+	for range time.NewTicker(time.Second * 10).C {
+		s.heartbeat()
+	}
+}
+
 func (s *Synchronizer) Register(app *Application) {
 	s.registerChan <- app
 }
@@ -107,84 +157,519 @@ func (s *Synchronizer) Deregister(resourceName string) {
 	s.deregisterChan <- resourceName
 }
 
+// heartbeatTarget is an instance to heartbeat, along with a copy of its application
+type heartbeatTarget struct {
+	app        Application
+	instance   *fargo.Instance
+	reregister bool
+}
+
 func (s *Synchronizer) heartbeat() {
+	for _, t := range s.heartbeatTargets() {
+		s.heartbeatInstance(&t.app, t.instance, t.reregister)
+	}
+}
 
+// heartbeatTargets returns the instances to heartbeat, the heartbeats are sent without holding the lock
+func (s *Synchronizer) heartbeatTargets() []heartbeatTarget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	var targets []heartbeatTarget
 	for _, app := range s.applications {
+		if s.heartbeatsPaused(app.Environment) {
+			continue
+		}
+
+		reregister := !s.frozen(app.Environment)
 		for _, i := range app.Instances {
-			uniqueId := i.UniqueID(*i)
+			targets = append(targets, heartbeatTarget{app: *app, instance: i, reregister: reregister})
+		}
+	}
 
-			totalHeartbeats.
-				WithLabelValues(app.Environment, app.Name, uniqueId).
+	// instances whose deregistration is queued by a freeze or held back by a guard are still registered
+	for key, op := range s.outbox {
+		if _, owned := s.owners[key]; owned || op.Type != OperationDeregister || s.heartbeatsPaused(op.Environment) {
+			continue
+		}
+
+		if op.Held || s.frozen(op.Environment) {
+			targets = append(targets, heartbeatTarget{
+				app:        Application{ResourceName: op.ResourceName, Environment: op.Environment, Name: op.Instance.App},
+				instance:   op.Instance,
+				reregister: !s.frozen(op.Environment),
+			})
+		}
+	}
+
+	return targets
+}
+
+func (s *Synchronizer) heartbeatInstance(app *Application, i *fargo.Instance, reregister bool) {
+	uniqueId := i.UniqueID(*i)
+
+	totalHeartbeats.
+		WithLabelValues(app.Environment, app.Name, uniqueId).
+		Inc()
+
+	log := s.log.WithValues("environment", app.Environment, "app", app.Name, "uniqueId", uniqueId)
+	log.Info("sending heartbeat request for instance")
+
+	err := s.client.HeartBeatInstance(app.Environment, i)
+	if client.IsNotFound(err) && reregister {
+		// registrations are only sent on changes, register again the instances Eureka lost
+		log.Info("instance unknown to eureka, registering it again")
+		s.reregister(app, i)
+		return
+	}
+
+	if err != nil {
+		log.Error(err, "unable to heartbeat instance")
+
+		heartbeatFailures.
+			WithLabelValues(app.Environment, app.Name, uniqueId).
+			Inc()
+	}
+}
+
+// reregister registers again an instance lost by Eureka in the background, since the registration waits for the
+// rate limit of the environment
+func (s *Synchronizer) reregister(app *Application, i *fargo.Instance) {
+	key := instanceKey(app.Environment, i)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reregistering[key] {
+		return
+	}
+	s.reregistering[key] = true
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.reregistering, key)
+		}()
+
+		s.client.Wait(app.Environment, client.PriorityLow)
+		err := s.client.ReregisterAsgInstance(app.Environment, i, app.AsgNames[i.InstanceId])
+		if err == nil && app.Status != fargo.UP {
+			s.client.Wait(app.Environment, client.PriorityLow)
+			err = s.client.UpdateInstanceStatus(app.Environment, i, app.Status)
+		}
+
+		if err != nil {
+			s.log.Error(err, "unable to register instance again", "environment", app.Environment, "app", app.Name, "uniqueId", i.UniqueID(*i))
+
+			heartbeatFailures.
+				WithLabelValues(app.Environment, app.Name, i.UniqueID(*i)).
 				Inc()
+		}
+	}()
+}
 
-			log := s.log.WithValues("environment", app.Environment, "app", app.Name, "uniqueId", uniqueId)
-			log.Info("sending heartbeat request for instance")
+// throttle waits for the rate limit of the environment without holding the lock, so the writes queue by priority
+// and don't block the heartbeats. Callers hold the lock, and check the state they rely on again once it returns.
+func (s *Synchronizer) throttle(environment string, p client.Priority) {
+	s.mu.Unlock()
+	defer s.mu.Lock()
 
-			if err := s.client.HeartBeatInstance(app.Environment, i); err != nil {
-				log.Error(err, "unable to heartbeat instance")
+	s.client.Wait(environment, p)
+}
 
-				heartbeatFailures.
-					WithLabelValues(app.Environment, app.Name, uniqueId).
-					Inc()
+func (s *Synchronizer) RegisterApplicationSync(n *Application) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.persist()
+
+	resourceName, key := n.ResourceName, n.key()
+	if len(n.Instances) == 0 {
+		return errors.New(fmt.Sprintf("error. Invalid application. No instances set to be registered. Resource: %s", resourceName))
+	}
+
+	s.resolveConflicts(n)
+
+	if n.Status == "" {
+		n.Status = fargo.UP
+	}
+
+	// only the instances removed, added or changed since the previous registration are sent to Eureka,
+	// they are queued until the freeze is lifted when the environment is frozen
+	frozen := s.frozen(n.Environment)
+	previousStatus := fargo.UP
+	var previous *Application
+	instances := n.Instances
+	if app, contains := s.applications[key]; contains {
+		removed := getInstancesToDeregister(app.Instances, n.Instances)
+		instances = getInstancesToRegister(app, n)
+		s.intend(OperationDeregister, app, removed)
+		s.intend(OperationRegister, n, instances)
+		s.flush()
+
+		// the previous application is replaced once registered, its instances keep being sent heartbeats
+		// while the registration waits for the rate limit, except the removed ones
+		retained := *app
+		for _, i := range removed {
+			retained.Instances = removeInstance(retained.Instances, i)
+		}
+		s.applications[key] = &retained
+
+		for _, i := range removed {
+			if !frozen {
+				s.settle(app.Environment, i, s.deregisterInstance(app, i))
 			}
+			s.release(app, i)
 		}
+
+		previousStatus = app.Status
+		previous = app
+	} else {
+		s.intend(OperationRegister, n, instances)
+		s.flush()
 	}
-}
 
-func (s *Synchronizer) RegisterApplicationSync(n *Application) error {
-	resourceName := n.ResourceName
+	if frozen && len(instances) > 0 {
+		s.log.Info("environment frozen, queueing registration", "environment", n.Environment, "resource", resourceName)
+		instances = nil
+	}
+
+	for _, i := range instances {
+		uniqueId := i.UniqueID(*i)
+
+		totalRegistrations.
+			WithLabelValues(n.Environment, n.Name, uniqueId).
+			Inc()
 
-	if app, contains := s.applications[resourceName]; !contains {
-		for _, i := range n.Instances {
-			uniqueId := i.UniqueID(*i)
+		log := s.log.WithValues("environment", n.Environment, "app", n.Name, "uniqueId", uniqueId)
+		log.Info("trying to register instance")
 
-			totalRegistrations.
+		s.throttle(n.Environment, client.PriorityLow)
+		err := s.client.ReregisterAsgInstance(n.Environment, i, n.AsgNames[i.InstanceId])
+		s.settle(n.Environment, i, err)
+
+		if err != nil {
+			log.Error(err, "unable to register instance")
+
+			registrationFailures.
 				WithLabelValues(n.Environment, n.Name, uniqueId).
 				Inc()
 
-			log := s.log.WithValues("environment", n.Environment, "app", n.Name, "uniqueId", uniqueId)
-			log.Info("trying to register instance")
+			return errors.New(fmt.Sprintf("error trying to register new application. Resource: %s", resourceName))
+		}
+	}
 
-			if err := s.client.RegisterInstance(n.Environment, i); err != nil {
-				log.Error(err, "unable to register instance")
+	for _, i := range n.Instances {
+		s.owners[instanceKey(n.Environment, i)] = key
+	}
 
-				registrationFailures.
-					WithLabelValues(n.Environment, n.Name, uniqueId).
-					Inc()
+	s.applications[key] = n
 
-				return errors.New(fmt.Sprintf("error trying to register new application. Resource: %s", resourceName))
-			} else {
-				s.applications[resourceName] = n
-				return nil
-			}
+	if frozen {
+		// the status is applied once the freeze is lifted and the resource reconciled again
+		n.Status = previousStatus
+		n.AsgStatus = ""
+		if previous != nil {
+			n.AsgStatus = previous.AsgStatus
 		}
-		return errors.New(fmt.Sprintf("error. Invalid application. No instances set to be registered. Resource: %s", resourceName))
-	} else {
-		instances := getInstancesToDeregister(app.Instances, n.Instances)
+		return nil
+	}
+
+	if err := s.applyStatus(n, previousStatus, instances); err != nil {
+		// keep the previous status so the override is retried on the next registration
+		n.Status = previousStatus
+		return err
+	}
+
+	if err := s.applyAsgStatus(n, previous); err != nil {
+		// the status of every ASG is set again on the next registration
+		n.AsgStatus = ""
+		return err
+	}
+
+	return nil
+}
+
+// applyAsgStatus sets the status of the ASGs of the application through Eureka's ASG API, only for the ASGs
+// whose status was not already set by the previous registration
+func (s *Synchronizer) applyAsgStatus(app *Application, previous *Application) error {
+	if app.AsgStatus == "" {
+		return nil
+	}
+
+	applied := make(map[string]bool)
+	if previous != nil && previous.AsgStatus == app.AsgStatus {
+		for _, asgName := range previous.AsgNames {
+			applied[asgName] = true
+		}
+	}
 
-		for _, i := range instances {
-			s.deregisterInstance(app, i)
+	for _, asgName := range app.AsgNames {
+		if applied[asgName] {
+			continue
 		}
+		applied[asgName] = true
+
+		log := s.log.WithValues("environment", app.Environment, "app", app.Name, "asgName", asgName)
+		log.Info("trying to update ASG status", "status", app.AsgStatus)
+		s.throttle(app.Environment, client.PriorityLow)
+		if err := s.client.UpdateAsgStatus(app.Environment, asgName, app.AsgStatus); err != nil {
+			log.Error(err, "unable to update ASG status")
 
-		delete(s.applications, resourceName)
-		return s.RegisterApplicationSync(n)
+			return errors.New(fmt.Sprintf("error trying to update ASG status. Resource: %s", app.ResourceName))
+		}
 	}
+
+	return nil
 }
 
-func (s *Synchronizer) deregister(key string) {
-	if app, ok := s.applications[key]; !ok {
-		s.log.Error(errors.New("unable to deregister app"), "app not found", "key", key)
-	} else {
+// applyStatus overrides the status of the instances through Eureka's status API, removing the
+// override once the application is back UP. Only the registered instances are updated while the status is unchanged.
+func (s *Synchronizer) applyStatus(app *Application, previous fargo.StatusType, registered []*fargo.Instance) error {
+	instances := app.Instances
+	if app.Status == previous {
+		if app.Status == fargo.UP {
+			return nil
+		}
+
+		instances = registered
+	}
+
+	for _, i := range instances {
+		uniqueId := i.UniqueID(*i)
+
+		totalStatusUpdates.
+			WithLabelValues(app.Environment, app.Name, uniqueId).
+			Inc()
+
+		log := s.log.WithValues("environment", app.Environment, "app", app.Name, "uniqueId", uniqueId)
+
+		s.throttle(app.Environment, client.PriorityLow)
+
+		var err error
+		if app.Status == fargo.UP {
+			log.Info("trying to remove status override of instance")
+			err = s.client.RemoveStatusOverride(app.Environment, i)
+		} else {
+			log.Info("trying to override status of instance", "status", app.Status)
+			err = s.client.UpdateInstanceStatus(app.Environment, i, app.Status)
+		}
+
+		if err != nil {
+			log.Error(err, "unable to update instance status")
+
+			statusUpdateFailures.
+				WithLabelValues(app.Environment, app.Name, uniqueId).
+				Inc()
+
+			return errors.New(fmt.Sprintf("error trying to update application status. Resource: %s", app.ResourceName))
+		}
+	}
+
+	return nil
+}
+
+// Get returns the application registered by the resource into the environment, or nil if there is none
+func (s *Synchronizer) Get(resourceName, environment string) *Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.applications[(&Application{ResourceName: resourceName, Environment: environment}).key()]
+	if !ok {
+		return nil
+	}
+
+	result := *app
+	return &result
+}
+
+// RetainEnvironments deregisters the applications of the resource from every environment not listed, including the
+// registered applications unknown to the synchronizer, i.e registered before a restart. An error is returned while
+// instances are left to deregister from those environments.
+func (s *Synchronizer) RetainEnvironments(resourceName string, environments []string, registered []*Application) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.persist()
+
+	retained := make(map[string]bool)
+	for _, e := range environments {
+		retained[e] = true
+	}
+
+	for _, app := range registered {
+		if !retained[app.Environment] {
+			s.adopt(app)
+		}
+	}
+
+	for key, app := range s.applications {
+		if app.ResourceName == resourceName && !retained[app.Environment] {
+			s.deregisterApplication(key, app)
+		}
+	}
+
+	s.revokeRegistrations(resourceName, func(environment string) bool { return !retained[environment] })
+
+	var left int
+	for _, op := range s.outbox {
+		if op.Type == OperationDeregister && op.ResourceName == resourceName && !retained[op.Environment] {
+			left++
+		}
+	}
+
+	if left > 0 {
+		return fmt.Errorf("%d instances left to deregister from the previous environments of resource %s", left, resourceName)
+	}
+
+	return nil
+}
+
+// adopt tracks an application registered before a restart, without the instances taken over by another resource
+func (s *Synchronizer) adopt(app *Application) {
+	if _, contains := s.applications[app.key()]; contains {
+		return
+	}
+
+	var instances []*fargo.Instance
+	for _, i := range app.Instances {
+		if _, owned := s.owners[instanceKey(app.Environment, i)]; !owned {
+			instances = append(instances, i)
+		}
+	}
+
+	if len(instances) > 0 {
+		app.Instances = instances
+		s.applications[app.key()] = app
+	}
+}
+
+func (s *Synchronizer) deregister(resourceName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.persist()
+
+	var found bool
+	for key, app := range s.applications {
+		if app.ResourceName == resourceName {
+			s.deregisterApplication(key, app)
+			found = true
+		}
+	}
+
+	s.revokeRegistrations(resourceName, func(string) bool { return true })
+
+	if !found {
+		s.log.Error(errors.New("unable to deregister app"), "app not found", "resource", resourceName)
+	}
+}
+
+func (s *Synchronizer) deregisterApplication(key string, app *Application) {
+	s.intend(OperationDeregister, app, app.Instances)
+	s.flush()
+
+	frozen := s.frozen(app.Environment)
+	for _, i := range app.Instances {
+		if !frozen {
+			s.settle(app.Environment, i, s.deregisterInstance(app, i))
+		}
+		s.release(app, i)
+	}
+
+	delete(s.applications, key)
+}
+
+// DeregisterSync deregisters every instance of the resource, including the given applications
+// registered before a restart. Instances failing to deregister are kept, and heartbeated, so they
+// are retried on the next call. An error is returned while any instance is left.
+func (s *Synchronizer) DeregisterSync(resourceName string, registered []*Application) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.persist()
+
+	for _, app := range registered {
+		s.adopt(app)
+	}
+
+	s.revokeRegistrations(resourceName, func(string) bool { return true })
+
+	var left, held int
+	for key, app := range s.applications {
+		if app.ResourceName != resourceName {
+			continue
+		}
+
+		if s.frozen(app.Environment) {
+			s.log.Info("environment frozen, waiting to deregister", "environment", app.Environment, "resource", resourceName)
+			left += len(app.Instances)
+			continue
+		}
+
+		s.intend(OperationDeregister, app, app.Instances)
+		s.flush()
+
+		var instances []*fargo.Instance
 		for _, i := range app.Instances {
-			s.deregisterInstance(app, i)
+			err := s.deregisterInstance(app, i)
+			s.settle(app.Environment, i, err)
+
+			if err != nil {
+				if errors.Is(err, ErrDeregistrationHeld) {
+					held++
+				}
+
+				instances = append(instances, i)
+				continue
+			}
+
+			s.release(app, i)
+		}
+
+		app.Instances = instances
+		if len(instances) == 0 {
+			delete(s.applications, key)
 		}
 
-		delete(s.applications, key)
+		left += len(instances)
+	}
+
+	if held > 0 {
+		return fmt.Errorf("%w: %d instances of resource %s", ErrDeregistrationHeld, held, resourceName)
+	}
+
+	if left > 0 {
+		return errors.New(fmt.Sprintf("error trying to deregister application. %d instances left. Resource: %s", left, resourceName))
+	}
+
+	return nil
+}
+
+// Applications returns the applications registered by the resource in every environment
+func (s *Synchronizer) Applications(resourceName string) []*Application {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []*Application
+	for _, app := range s.applications {
+		if app.ResourceName == resourceName {
+			a := *app
+			result = append(result, &a)
+		}
 	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Environment < result[j].Environment })
+
+	return result
+}
+
+// deregisterInstance deregisters the instance from Eureka once allowed by the rate limit, instances already gone
+// are deregistered
+func (s *Synchronizer) deregisterInstance(app *Application, i *fargo.Instance) error {
+	s.throttle(app.Environment, client.PriorityHigh)
+
+	return s.sendDeregistration(app, i)
 }
 
-func (s *Synchronizer) deregisterInstance(app *Application, i *fargo.Instance) {
+// sendDeregistration deregisters the instance from Eureka unless held back by the guard of the environment
+func (s *Synchronizer) sendDeregistration(app *Application, i *fargo.Instance) error {
 	uniqueId := i.UniqueID(*i)
 
 	totalDeregistrations.
@@ -192,19 +677,32 @@ func (s *Synchronizer) deregisterInstance(app *Application, i *fargo.Instance) {
 		Inc()
 
 	log := s.log.WithValues("environment", app.Environment, "app", app.Name, "uniqueId", uniqueId)
+
+	if s.holdDeregistration(app.ResourceName, app.Environment) {
+		log.Info("holding back instance deregistration")
+		return ErrDeregistrationHeld
+	}
+
 	log.Info("trying to deregister instance")
 
-	if err := s.client.DeregisterInstance(app.Environment, i); err != nil {
+	if err := s.client.DeregisterInstance(app.Environment, i); err != nil && !client.IsNotFound(err) {
 		log.Error(err, "unable to deregister instance")
 
 		deregistrationFailures.
 			WithLabelValues(app.Environment, app.Name, uniqueId).
 			Inc()
+
+		return err
 	}
+
+	s.recordDeregistration(app.Environment)
+
+	return nil
 }
 
 func (s *Synchronizer) Start() {
 	go s.goProcess()
+	go s.goHeartbeat()
 }
 
 func (s *Synchronizer) Stop() {
@@ -0,0 +1,134 @@
+package sync
+
+import (
+	"github.com/eurek8s/controller/internal/eureka/client"
+	"github.com/go-logr/logr"
+	"github.com/hudl/fargo"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eurekaServer is a fake Eureka accepting every call, recording the heartbeats
+type eurekaServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	heartbeats []string
+}
+
+func newEurekaServer() *eurekaServer {
+	e := &eurekaServer{}
+	e.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPut && !strings.Contains(r.URL.Path, "/status"):
+			e.mu.Lock()
+			e.heartbeats = append(e.heartbeats, r.URL.Path)
+			e.mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	return e
+}
+
+func (e *eurekaServer) heartbeatCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.heartbeats)
+}
+
+func testApplication(resourceName string, instanceIDs ...string) *Application {
+	app := &Application{ResourceName: resourceName, Environment: "qa", Name: "ORDERS", Status: fargo.UP}
+	for _, id := range instanceIDs {
+		app.Instances = append(app.Instances, &fargo.Instance{
+			UniqueID:   uniqueID,
+			InstanceId: id,
+			HostName:   id + ".example.com",
+			App:        "ORDERS",
+			Status:     fargo.UP,
+		})
+	}
+
+	return app
+}
+
+func TestHeartbeatNotBlockedByRateLimitedWrites(t *testing.T) {
+	server := newEurekaServer()
+	defer server.Close()
+
+	c := client.New(map[string][]string{"qa": {server.URL}})
+	c.SetRateLimit("qa", 0.001, 1)
+	s := New(c, logr.Discard())
+
+	if err := s.RegisterApplicationSync(testApplication("default/orders", "orders-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the burst is spent, this registration waits for the rate limit
+	go func() {
+		_ = s.RegisterApplicationSync(testApplication("default/payments", "payments-1"))
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		s.heartbeat()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("heartbeat blocked behind a queued write")
+	}
+
+	if got := server.heartbeatCount(); got != 1 {
+		t.Errorf("sent %d heartbeats, want 1", got)
+	}
+}
+
+func TestHeartbeatContinuesWhileReregistering(t *testing.T) {
+	server := newEurekaServer()
+	defer server.Close()
+
+	c := client.New(map[string][]string{"qa": {server.URL}})
+	c.SetRateLimit("qa", 0.001, 2)
+	s := New(c, logr.Discard())
+
+	if err := s.RegisterApplicationSync(testApplication("default/orders", "orders-1", "orders-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the burst is spent, the registration of the changed instance waits for the rate limit
+	changed := testApplication("default/orders", "orders-1", "orders-2")
+	changed.Instances[0].HomePageUrl = "https://orders-1.example.com/home"
+	go func() {
+		_ = s.RegisterApplicationSync(changed)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		s.heartbeat()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("heartbeat blocked behind a queued write")
+	}
+
+	// the instances of the previous registration are still sent heartbeats
+	if got := server.heartbeatCount(); got != 2 {
+		t.Errorf("sent %d heartbeats, want 2", got)
+	}
+}
@@ -0,0 +1,344 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	discoveryv1 "github.com/eurek8s/controller/api/v1"
+	"github.com/eurek8s/controller/internal/eureka/config"
+	eurekahandler "github.com/eurek8s/controller/internal/eureka/handler"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"net/url"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+const appNameField = ".spec.appName"
+
+//+kubebuilder:webhook:path=/mutate-discovery-eurek8s-com-v1-eurekaapplication,mutating=true,failurePolicy=fail,sideEffects=None,groups=discovery.eurek8s.com,resources=eurekaapplications,verbs=create;update,versions=v1,name=meurekaapplication.eurek8s.com,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-discovery-eurek8s-com-v1-eurekaapplication,mutating=false,failurePolicy=fail,sideEffects=None,groups=discovery.eurek8s.com,resources=eurekaapplications,verbs=create;update,versions=v1,name=veurekaapplication.eurek8s.com,admissionReviewVersions=v1
+
+// EurekaApplicationWebhook defaults and validates EurekaApplication resources
+type EurekaApplicationWebhook struct {
+	client               client.Client
+	environments         config.Config
+	namespaceEnvironment eurekahandler.NamespaceEnvironment
+}
+
+// SetupWithManager registers the defaulting and validating webhooks with the Manager.
+func SetupWithManager(
+	mgr ctrl.Manager,
+	environments config.Config,
+	namespaceEnvironment eurekahandler.NamespaceEnvironment,
+) error {
+	err := mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		&discoveryv1.EurekaApplication{},
+		appNameField,
+		appNames,
+	)
+	if err != nil {
+		return err
+	}
+
+	w := &EurekaApplicationWebhook{
+		client:               mgr.GetClient(),
+		environments:         environments,
+		namespaceEnvironment: namespaceEnvironment,
+	}
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&discoveryv1.EurekaApplication{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// appNames indexes the applications by the app name they are registered with, resolved by the last reconcile, and
+// by the name in their spec until they are reconciled
+func appNames(o client.Object) []string {
+	app := o.(*discoveryv1.EurekaApplication)
+	names := []string{app.Spec.AppName}
+	if s := app.Status.EffectiveSpec; s != nil && s.AppName != app.Spec.AppName {
+		names = append(names, s.AppName)
+	}
+
+	return names
+}
+
+// Default fills in the built-in environment, zone and paths left empty by the spec and every defaults resource,
+// the values of the defaults resources being resolved at every reconcile instead
+func (w *EurekaApplicationWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	app, ok := obj.(*discoveryv1.EurekaApplication)
+	if !ok {
+		return fmt.Errorf("expected an EurekaApplication but got a %T", obj)
+	}
+
+	if !app.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	err := eurekahandler.ApplyBuiltinDefaults(ctx, w.client, app, w.namespaceEnvironment)
+	// invalid templates are rejected by the validating webhook
+	if eurekahandler.Classify(err) == eurekahandler.CategoryInvalidSpec {
+		return nil
+	}
+
+	return err
+}
+
+func (w *EurekaApplicationWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return w.validate(ctx, obj)
+}
+
+func (w *EurekaApplicationWebhook) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) error {
+	return w.validate(ctx, newObj)
+}
+
+func (w *EurekaApplicationWebhook) ValidateDelete(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+func (w *EurekaApplicationWebhook) validate(ctx context.Context, obj runtime.Object) error {
+	app, ok := obj.(*discoveryv1.EurekaApplication)
+	if !ok {
+		return fmt.Errorf("expected an EurekaApplication but got a %T", obj)
+	}
+
+	// never block the finalizer removal of a resource being deleted
+	if !app.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	if app.Spec.AppName == "" {
+		errs = append(errs, field.Required(spec.Child("appName"), "name of the app to be registered in Eureka"))
+	}
+
+	if app.Spec.IngressName == "" && len(app.Spec.Ingresses) == 0 && app.Spec.IngressSelector == nil {
+		errs = append(errs, field.Required(spec.Child("ingressName"), "name of the ingress to be registered in Eureka, or ingresses or ingressSelector"))
+	}
+
+	if app.Spec.IngressSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(app.Spec.IngressSelector); err != nil {
+			errs = append(errs, field.Invalid(spec.Child("ingressSelector"), app.Spec.IngressSelector, err.Error()))
+		}
+	}
+
+	// the app name and the environment may come from templates and defaults, the resolved ones are validated
+	effectiveSpec, err := eurekahandler.EffectiveSpec(ctx, w.client, app, w.namespaceEnvironment)
+	if err != nil {
+		if eurekahandler.Classify(err) != eurekahandler.CategoryInvalidSpec {
+			return apierrors.NewInternalError(err)
+		}
+
+		errs = append(errs, field.Invalid(spec.Child("appName"), app.Spec.AppName, err.Error()))
+		return apierrors.NewInvalid(discoveryv1.GroupVersion.WithKind("EurekaApplication").GroupKind(), app.Name, errs)
+	}
+	effective := app.DeepCopy()
+	effective.Spec = *effectiveSpec
+
+	if len(app.Spec.Environments) == 0 {
+		if _, ok := w.environments[effective.Spec.Environment]; !ok {
+			errs = append(errs, field.NotSupported(spec.Child("environment"), effective.Spec.Environment, w.environmentKeys()))
+		}
+	}
+
+	names := make(map[string]bool)
+	for idx, e := range app.Spec.Environments {
+		envPath := spec.Child("environments").Index(idx)
+
+		if e.Name == "" {
+			errs = append(errs, field.Required(envPath.Child("name"), "name of the environment to register the instances into"))
+		} else if _, ok := w.environments[e.Name]; !ok {
+			errs = append(errs, field.NotSupported(envPath.Child("name"), e.Name, w.environmentKeys()))
+		} else if names[e.Name] {
+			errs = append(errs, field.Duplicate(envPath.Child("name"), e.Name))
+		}
+		names[e.Name] = true
+
+		if e.Paths != nil {
+			errs = append(errs, validatePaths(*e.Paths, envPath.Child("paths"))...)
+		}
+	}
+
+	for idx, environment := range eurekahandler.Environments(effective) {
+		envPath := spec.Child("environment")
+		if len(app.Spec.Environments) > 0 {
+			envPath = spec.Child("environments").Index(idx).Child("name")
+		}
+
+		if err := eurekahandler.CheckPolicy(ctx, w.client, app.Namespace, effective.Spec.AppName, environment); err != nil {
+			if eurekahandler.Classify(err) != eurekahandler.CategoryPolicyDenied {
+				return apierrors.NewInternalError(err)
+			}
+
+			errs = append(errs, field.Forbidden(envPath, err.Error()))
+		}
+	}
+
+	errs = append(errs, validatePaths(app.Spec.Paths, spec.Child("paths"))...)
+
+	if mw := app.Spec.MaintenanceWindow; mw != nil && !mw.End.After(mw.Start.Time) {
+		errs = append(errs, field.Invalid(spec.Child("maintenanceWindow", "end"), mw.End, "must be after start"))
+	}
+
+	if zd := app.Spec.ZoneDetection; zd != nil && zd.Source == discoveryv1.ZoneSourceIngressController && zd.IngressControllerSelector == nil {
+		errs = append(errs, field.Required(spec.Child("zoneDetection", "ingressControllerSelector"), "required to detect the zone from the ingress controller"))
+	}
+
+	errs = append(errs, eurekahandler.ValidateInstanceTemplates(effective, spec)...)
+
+	if app.Spec.AsgStatus != "" && app.Spec.AsgName == "" {
+		errs = append(errs, field.Required(spec.Child("asgName"), "name of the ASG whose status is set by asgStatus"))
+	}
+
+	if t := app.Spec.DeregistrationTimeout; t != nil && t.Duration < 0 {
+		errs = append(errs, field.Invalid(spec.Child("deregistrationTimeout"), t.Duration.String(), "must not be negative"))
+	}
+
+	if t := app.Spec.TTL; t != nil && t.Duration <= 0 {
+		errs = append(errs, field.Invalid(spec.Child("ttl"), t.Duration.String(), "must be positive"))
+	}
+
+	if len(errs) == 0 {
+		errs = appendIfInvalid(errs, w.validateUniqueHosts(ctx, effective))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(discoveryv1.GroupVersion.WithKind("EurekaApplication").GroupKind(), app.Name, errs)
+}
+
+// validateUniqueHosts rejects applications registering a host already registered with
+// the same app name and in a shared environment by another resource, in any namespace
+func (w *EurekaApplicationWebhook) validateUniqueHosts(ctx context.Context, app *discoveryv1.EurekaApplication) *field.Error {
+	hosts, err := w.ingressHosts(ctx, app)
+	if err != nil {
+		return field.InternalError(field.NewPath("spec", "ingressName"), err)
+	}
+
+	var apps discoveryv1.EurekaApplicationList
+	if err := w.client.List(ctx, &apps, client.MatchingFields{appNameField: app.Spec.AppName}); err != nil {
+		return field.InternalError(field.NewPath("spec", "appName"), err)
+	}
+
+	environments := make(map[string]bool)
+	for _, e := range eurekahandler.Environments(app) {
+		environments[e] = true
+	}
+
+	for _, other := range apps.Items {
+		if other.Namespace == app.Namespace && other.Name == app.Name {
+			continue
+		}
+
+		// the other application is compared with its resolved app name and environments as well
+		otherSpec, err := eurekahandler.EffectiveSpec(ctx, w.client, &other, w.namespaceEnvironment)
+		if err != nil {
+			if eurekahandler.Classify(err) != eurekahandler.CategoryInvalidSpec {
+				return field.InternalError(field.NewPath("spec", "appName"), err)
+			}
+			continue
+		}
+		other.Spec = *otherSpec
+
+		if other.Spec.AppName != app.Spec.AppName || !sharesEnvironment(environments, &other) {
+			continue
+		}
+
+		otherHosts, err := w.ingressHosts(ctx, &other)
+		if err != nil {
+			return field.InternalError(field.NewPath("spec", "ingressName"), err)
+		}
+
+		for host := range hosts {
+			if _, ok := otherHosts[host]; ok {
+				return field.Duplicate(
+					field.NewPath("spec", "appName"),
+					fmt.Sprintf("%s already registered for host %s by %s/%s", app.Spec.AppName, host, other.Namespace, other.Name),
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ingressHosts returns the hosts of the ingresses referenced by the application, none if they do not exist yet
+func (w *EurekaApplicationWebhook) ingressHosts(ctx context.Context, app *discoveryv1.EurekaApplication) (map[string]struct{}, error) {
+	hosts := make(map[string]struct{})
+
+	ingresses, err := eurekahandler.Ingresses(ctx, w.client, app)
+	if err != nil {
+		return hosts, client.IgnoreNotFound(err)
+	}
+
+	for _, ingress := range ingresses {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host != "" {
+				hosts[strings.ToLower(rule.Host)] = struct{}{}
+			}
+		}
+	}
+
+	return hosts, nil
+}
+
+func (w *EurekaApplicationWebhook) environmentKeys() []string {
+	var keys []string
+	for k := range w.environments {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sharesEnvironment(environments map[string]bool, app *discoveryv1.EurekaApplication) bool {
+	for _, e := range eurekahandler.Environments(app) {
+		if environments[e] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validatePaths(paths discoveryv1.EurekaApplicationPaths, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	errs = appendIfInvalid(errs, validatePath(paths.Home, fldPath.Child("home")))
+	errs = appendIfInvalid(errs, validatePath(paths.Status, fldPath.Child("status")))
+	errs = appendIfInvalid(errs, validatePath(paths.HealthCheck, fldPath.Child("healthcheck")))
+
+	return errs
+}
+
+func validatePath(p string, fldPath *field.Path) *field.Error {
+	if p == "" {
+		return nil
+	}
+
+	u, err := url.Parse(p)
+	if err != nil || !strings.HasPrefix(p, "/") || u.Host != "" || u.RawQuery != "" || u.Fragment != "" {
+		return field.Invalid(fldPath, p, "must be an absolute path (i.e /actuator/health)")
+	}
+
+	return nil
+}
+
+func appendIfInvalid(errs field.ErrorList, err *field.Error) field.ErrorList {
+	if err != nil {
+		return append(errs, err)
+	}
+
+	return errs
+}
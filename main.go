@@ -17,7 +17,6 @@ limitations under the License.
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"flag"
 	"os"
@@ -27,9 +26,12 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	eurekaclient "github.com/eurek8s/controller/internal/eureka/client"
+	eurekaconfig "github.com/eurek8s/controller/internal/eureka/config"
 	eurekahandler "github.com/eurek8s/controller/internal/eureka/handler"
 	eurek8ssyncer "github.com/eurek8s/controller/internal/eureka/sync"
+	eurekawebhook "github.com/eurek8s/controller/internal/eureka/webhook"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -57,11 +59,17 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var namespaceEnvironment eurekahandler.NamespaceEnvironment
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&namespaceEnvironment.Label, "namespace-environment-label", "",
+		"Label of the namespaces holding the environment of the applications leaving it empty.")
+	flag.StringVar(&namespaceEnvironment.Annotation, "namespace-environment-annotation", "",
+		"Annotation of the namespaces holding the environment of the applications leaving it empty, "+
+			"taking precedence over the label.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -79,18 +87,37 @@ func main() {
 
 	setupLog.Info("Loaded config: " + config)
 
-	eurekaAddresses := make(map[string][]string)
-	err := json.Unmarshal([]byte(config), &eurekaAddresses)
-	if err != nil || len(eurekaAddresses) == 0 {
-		setupLog.Error(errors.New("empty config"), "unable to use the provided configuration")
+	eurekaConfig, err := eurekaconfig.Parse(config)
+	if err != nil {
+		setupLog.Error(err, "unable to use the provided configuration")
 		os.Exit(1)
 	}
 
-	syncer := eurek8ssyncer.New(
-		eurekaclient.New(eurekaAddresses),
-		ctrl.Log.WithName("syncer"),
-	)
-	handler := eurekahandler.New(syncer, ctrl.Log.WithName("handler"))
+	eurekaClient := eurekaclient.New(eurekaConfig.Addresses())
+	for env, e := range eurekaConfig {
+		if r := e.RateLimit; r != nil {
+			eurekaClient.SetRateLimit(env, r.WritesPerSecond, r.Burst)
+		}
+	}
+
+	syncer := eurek8ssyncer.New(eurekaClient, ctrl.Log.WithName("syncer"))
+	handler := eurekahandler.New(syncer, eurekaConfig, ctrl.Log.WithName("handler"))
+	handler.NamespaceEnvironment = namespaceEnvironment
+
+	for env, e := range eurekaConfig {
+		if e.Frozen {
+			syncer.SetFreeze("config/"+env, eurek8ssyncer.Freeze{Environments: []string{env}, PauseHeartbeats: e.PauseHeartbeats})
+		}
+
+		if g := e.DeregistrationGuard; g != nil {
+			syncer.SetDeregistrationGuard(env, eurek8ssyncer.DeregistrationGuard{
+				MaxInstances: g.MaxInstances,
+				MaxPercent:   g.MaxPercent,
+				MinInstances: g.MinInstances,
+				Window:       g.WindowDuration(),
+			})
+		}
+	}
 
 	syncer.Start()
 
@@ -109,6 +136,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	// pending Eureka operations are kept in a ConfigMap of the controller namespace to survive restarts
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		store := &eurek8ssyncer.ConfigMapStore{
+			Client: mgr.GetClient(),
+			Reader: mgr.GetAPIReader(),
+			Key:    types.NamespacedName{Namespace: namespace, Name: "eurek8s-outbox"},
+		}
+		if err := mgr.Add(&eurek8ssyncer.OutboxPersister{Synchronizer: syncer, Store: store}); err != nil {
+			setupLog.Error(err, "unable to set up the persistence of pending eureka operations")
+			os.Exit(1)
+		}
+
+		if err = (&controllers.FreezeReconciler{
+			Client:       mgr.GetClient(),
+			Log:          ctrl.Log.WithName("controllers").WithName("Freeze"),
+			Key:          types.NamespacedName{Namespace: namespace, Name: "eurek8s-freeze"},
+			EurekaSyncer: syncer,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Freeze")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controllers.EurekaApplicationReconciler{
 		Client:        mgr.GetClient(),
 		Log:           ctrl.Log.WithName("controllers").WithName("EurekaApplication"),
@@ -119,6 +169,21 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "EurekaApplication")
 		os.Exit(1)
 	}
+	if err = (&controllers.IngressReconciler{
+		Client:        mgr.GetClient(),
+		Log:           ctrl.Log.WithName("controllers").WithName("Ingress"),
+		Scheme:        mgr.GetScheme(),
+		EventRecorder: mgr.GetEventRecorderFor("eurek8s-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Ingress")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		if err = eurekawebhook.SetupWithManager(mgr, eurekaConfig, namespaceEnvironment); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "EurekaApplication")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {